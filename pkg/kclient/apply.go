@@ -0,0 +1,195 @@
+package kclient
+
+import (
+	"bytes"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+// APIObject wraps the GroupVersionKind, namespace and name of a resource that
+// was created, updated or deleted by ApplyManifest/DeleteManifest, so callers
+// can report back what actually happened on the cluster.
+type APIObject struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+}
+
+// applyOrder ranks well-known kinds so that objects other resources depend on
+// (Namespaces, RBAC, Secrets, ...) are created before the objects that
+// reference them (Deployments, Services, Ingresses). Kinds not listed here
+// (including CRD-defined custom resources) sort after everything else, but
+// ahead of nothing - i.e. they are applied last, once their CRD is assumed to
+// already exist on the cluster.
+var applyOrder = map[string]int{
+	"Namespace":                0,
+	"CustomResourceDefinition": 1,
+	"ClusterRole":              2,
+	"ClusterRoleBinding":       2,
+	"Role":                     2,
+	"RoleBinding":              2,
+	"ServiceAccount":           2,
+	"Secret":                   3,
+	"ConfigMap":                3,
+	"PersistentVolumeClaim":    3,
+	"Service":                  4,
+	"Deployment":               5,
+	"StatefulSet":              5,
+	"DaemonSet":                5,
+	"Ingress":                  6,
+}
+
+// sortAPIObjects orders unstructured objects so that they can be created in
+// dependency order, e.g. a Namespace before the Deployment that lives in it,
+// or a Secret before the Deployment that mounts it.
+func sortAPIObjects(objs []*unstructured.Unstructured) []*unstructured.Unstructured {
+	rank := func(obj *unstructured.Unstructured) int {
+		if r, ok := applyOrder[obj.GetKind()]; ok {
+			return r
+		}
+		return len(applyOrder)
+	}
+
+	sorted := make([]*unstructured.Unstructured, len(objs))
+	copy(sorted, objs)
+
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && rank(sorted[j-1]) > rank(sorted[j]); j-- {
+			sorted[j-1], sorted[j] = sorted[j], sorted[j-1]
+		}
+	}
+
+	return sorted
+}
+
+// decodeManifest splits a YAML/JSON manifest containing one or more
+// "---"-separated documents into unstructured objects.
+func decodeManifest(manifest []byte) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	for {
+		var rawObj runtime.RawExtension
+		if err := decoder.Decode(&rawObj); err != nil {
+			if err.Error() == "EOF" {
+				break
+			}
+			return nil, errors.Wrap(err, "unable to decode manifest")
+		}
+		if len(bytes.TrimSpace(rawObj.Raw)) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+		if _, _, err := unstructured.UnstructuredJSONScheme.Decode(rawObj.Raw, nil, obj); err != nil {
+			return nil, errors.Wrap(err, "unable to decode manifest into an unstructured object")
+		}
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// resourceFor resolves the namespaced dynamic.ResourceInterface for the given
+// unstructured object using the Client's RESTMapper.
+func (c *Client) resourceFor(obj *unstructured.Unstructured) (dynamic.NamespaceableResourceInterface, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := c.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find REST mapping for %s", gvk)
+	}
+	return c.DynamicClient.Resource(mapping.Resource), nil
+}
+
+// ApplyManifest creates (or updates, if it already exists) every resource
+// described in the given YAML/JSON manifest, which may contain multiple
+// "---"-separated documents, including CRDs and the custom resources they
+// define. Resources are applied in dependency order so that, for example, a
+// Namespace or Secret a Deployment depends on is created first.
+func (c *Client) ApplyManifest(manifest []byte) ([]APIObject, error) {
+	objs, err := decodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	objs = sortAPIObjects(objs)
+
+	var applied []APIObject
+	for _, obj := range objs {
+		resource, err := c.resourceFor(obj)
+		if err != nil {
+			return applied, err
+		}
+
+		ns := obj.GetNamespace()
+		var resourceClient dynamic.ResourceInterface = resource
+		if ns != "" {
+			resourceClient = resource.Namespace(ns)
+		}
+
+		glog.V(4).Infof("Applying %s %s/%s", obj.GroupVersionKind(), ns, obj.GetName())
+
+		existing, getErr := resourceClient.Get(obj.GetName(), metav1.GetOptions{})
+		switch {
+		case getErr == nil:
+			obj.SetResourceVersion(existing.GetResourceVersion())
+			if _, err := resourceClient.Update(obj, metav1.UpdateOptions{}); err != nil {
+				return applied, errors.Wrapf(err, "unable to update %s %s/%s", obj.GroupVersionKind(), ns, obj.GetName())
+			}
+		case kerrors.IsNotFound(getErr):
+			if _, err := resourceClient.Create(obj, metav1.CreateOptions{}); err != nil {
+				return applied, errors.Wrapf(err, "unable to create %s %s/%s", obj.GroupVersionKind(), ns, obj.GetName())
+			}
+		default:
+			return applied, errors.Wrapf(getErr, "unable to get %s %s/%s", obj.GroupVersionKind(), ns, obj.GetName())
+		}
+
+		applied = append(applied, APIObject{
+			GroupVersionKind: obj.GroupVersionKind(),
+			Namespace:        ns,
+			Name:             obj.GetName(),
+		})
+	}
+
+	return applied, nil
+}
+
+// DeleteManifest deletes every resource described in the given manifest, in
+// the reverse of the order ApplyManifest would have created them in, so that
+// dependent resources are removed before the resources they depend on.
+func (c *Client) DeleteManifest(manifest []byte) error {
+	objs, err := decodeManifest(manifest)
+	if err != nil {
+		return err
+	}
+	objs = sortAPIObjects(objs)
+
+	for i := len(objs) - 1; i >= 0; i-- {
+		obj := objs[i]
+		resource, err := c.resourceFor(obj)
+		if err != nil {
+			return err
+		}
+
+		ns := obj.GetNamespace()
+		var resourceClient dynamic.ResourceInterface = resource
+		if ns != "" {
+			resourceClient = resource.Namespace(ns)
+		}
+
+		glog.V(4).Infof("Deleting %s %s/%s", obj.GroupVersionKind(), ns, obj.GetName())
+		if err := resourceClient.Delete(obj.GetName(), &metav1.DeleteOptions{}); err != nil {
+			return errors.Wrapf(err, "unable to delete %s %s/%s", obj.GroupVersionKind(), ns, obj.GetName())
+		}
+	}
+
+	return nil
+}
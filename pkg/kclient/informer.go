@@ -0,0 +1,261 @@
+package kclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/redhat-developer/odo-fork/pkg/log"
+)
+
+// informerResyncPeriod controls how often the shared informers do a full
+// relist against the API server to correct for any missed watch events. The
+// indexers serve reads from their in-memory cache the rest of the time.
+const informerResyncPeriod = 10 * time.Minute
+
+// informerFactory lazily builds a SharedInformerFactory scoped to
+// c.Namespace, backing GetNamespaceNames, ListSecrets, ListIngresses,
+// GetDeploymentsFromSelector, WaitAndGetPod and WaitAndGetSecret with lister
+// lookups instead of a fresh List/Get/Watch call per call, which otherwise
+// gets expensive fast in odo's inner loops (push/watch).
+func (c *Client) informerFactory() informers.SharedInformerFactory {
+	c.informerFactoryOnce.Do(func() {
+		c.informerStopCh = make(chan struct{})
+		c.sharedInformerFactory = informers.NewSharedInformerFactoryWithOptions(
+			c.KubeClient,
+			informerResyncPeriod,
+			informers.WithNamespace(c.Namespace),
+		)
+		// Touching each informer registers it with the factory so Start/
+		// WaitForCacheSync below actually sync it.
+		c.sharedInformerFactory.Core().V1().Namespaces().Informer()
+		c.sharedInformerFactory.Core().V1().Secrets().Informer()
+		c.sharedInformerFactory.Core().V1().Pods().Informer()
+		c.sharedInformerFactory.Apps().V1().Deployments().Informer()
+		c.sharedInformerFactory.Extensions().V1beta1().Ingresses().Informer()
+	})
+	return c.sharedInformerFactory
+}
+
+// Start starts the Client's shared informers, if they haven't been started
+// yet, and blocks until their caches have synced or stopCh is closed. The
+// informers themselves are started against c.informerStopCh, a long-lived
+// channel owned by the Client, not stopCh - stopCh only bounds this call's
+// wait for the caches to sync, since callers like WaitAndGetPod close their
+// own stopCh on return, and closing the informers' own channel would
+// permanently kill them for every later caller.
+func (c *Client) Start(stopCh <-chan struct{}) error {
+	factory := c.informerFactory()
+
+	c.informerStartOnce.Do(func() {
+		factory.Start(c.informerStopCh)
+	})
+
+	synced := factory.WaitForCacheSync(stopCh)
+	for informerType, ok := range synced {
+		if !ok {
+			return errors.Errorf("cache for %v never synced", informerType)
+		}
+	}
+	return nil
+}
+
+// GetNamespaceNames return list of existing namespaces that user has access to.
+func (c *Client) GetNamespaceNames() ([]string, error) {
+	namespaces, err := c.informerFactory().Core().V1().Namespaces().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list namespaces")
+	}
+
+	var namespaceNames []string
+	for _, ns := range namespaces {
+		namespaceNames = append(namespaceNames, ns.Name)
+	}
+	return namespaceNames, nil
+}
+
+// ListSecrets lists all the secrets in c.Namespace matching
+// labelSelector, served from the shared informer's local indexer.
+func (c *Client) ListSecrets(labelSelector string) ([]corev1.Secret, error) {
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid label selector %q", labelSelector)
+	}
+
+	secrets, err := c.informerFactory().Core().V1().Secrets().Lister().Secrets(c.Namespace).List(selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get secret list")
+	}
+
+	result := make([]corev1.Secret, 0, len(secrets))
+	for _, s := range secrets {
+		result = append(result, *s)
+	}
+	return result, nil
+}
+
+// GetDeploymentsFromSelector returns an array of Deployment resources which match the given selector. Returns the Deployments in c.Namespace
+// matching selector, served from the shared informer's local indexer.
+func (c *Client) GetDeploymentsFromSelector(selector string) ([]appsv1.Deployment, error) {
+	labelSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid label selector %q", selector)
+	}
+
+	deployments, err := c.informerFactory().Apps().V1().Deployments().Lister().Deployments(c.Namespace).List(labelSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list Deployments")
+	}
+
+	result := make([]appsv1.Deployment, 0, len(deployments))
+	for _, d := range deployments {
+		result = append(result, *d)
+	}
+	return result, nil
+}
+
+// ListIngresses lists all the ingresses in c.Namespace matching
+// labelSelector, served from the shared informer's local indexer.
+func (c *Client) ListIngresses(labelSelector string) ([]extensionsv1.Ingress, error) {
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid label selector %q", labelSelector)
+	}
+
+	ingresses, err := c.informerFactory().Extensions().V1beta1().Ingresses().Lister().Ingresses(c.Namespace).List(selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get ingress list")
+	}
+
+	result := make([]extensionsv1.Ingress, 0, len(ingresses))
+	for _, i := range ingresses {
+		result = append(result, *i)
+	}
+	return result, nil
+}
+
+// WaitAndGetPod subscribes to the shared Pod informer and blocks until
+// a pod matching selector reaches desiredPhase, instead of opening an ad-hoc
+// watch per call.
+func (c *Client) WaitAndGetPod(selector string, desiredPhase corev1.PodPhase, waitMessage string) (*corev1.Pod, error) {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid label selector %q", selector)
+	}
+
+	s := log.Spinner(waitMessage)
+	defer s.End(false)
+
+	podInformer := c.informerFactory().Core().V1().Pods().Informer()
+
+	podChannel := make(chan *corev1.Pod, 1)
+	watchErrorChannel := make(chan error, 1)
+	var once sync.Once
+
+	matches := func(obj interface{}) (*corev1.Pod, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || !sel.Matches(labels.Set(pod.Labels)) {
+			return nil, false
+		}
+		return pod, true
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := matches(obj); ok && pod.Status.Phase == desiredPhase {
+				once.Do(func() { podChannel <- pod })
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			pod, ok := matches(newObj)
+			if !ok {
+				return
+			}
+			switch pod.Status.Phase {
+			case desiredPhase:
+				once.Do(func() { podChannel <- pod })
+			case corev1.PodFailed, corev1.PodUnknown:
+				once.Do(func() { watchErrorChannel <- errors.Errorf("pod %s status %s", pod.Name, pod.Status.Phase) })
+			}
+		},
+	}
+
+	reg, err := podInformer.AddEventHandler(handler)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to subscribe to pod informer")
+	}
+	defer podInformer.RemoveEventHandler(reg)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := c.Start(stopCh); err != nil {
+		return nil, err
+	}
+
+	select {
+	case pod := <-podChannel:
+		s.End(true)
+		return pod, nil
+	case err := <-watchErrorChannel:
+		return nil, err
+	case <-time.After(waitForPodTimeOut):
+		return nil, errors.Errorf("waited %s but couldn't find running pod matching selector: '%s'", waitForPodTimeOut, selector)
+	}
+}
+
+// WaitAndGetSecret subscribes to the shared Secret informer and blocks
+// until the named secret is available, instead of opening an ad-hoc watch.
+func (c *Client) WaitAndGetSecret(name string, namespace string) (*corev1.Secret, error) {
+	secretInformer := c.informerFactory().Core().V1().Secrets().Informer()
+
+	secretChannel := make(chan *corev1.Secret, 1)
+	var once sync.Once
+
+	matches := func(obj interface{}) (*corev1.Secret, bool) {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok || secret.Namespace != namespace || secret.Name != name {
+			return nil, false
+		}
+		return secret, true
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if secret, ok := matches(obj); ok {
+				once.Do(func() { secretChannel <- secret })
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if secret, ok := matches(newObj); ok {
+				once.Do(func() { secretChannel <- secret })
+			}
+		},
+	}
+
+	reg, err := secretInformer.AddEventHandler(handler)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to subscribe to secret informer")
+	}
+	defer secretInformer.RemoveEventHandler(reg)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := c.Start(stopCh); err != nil {
+		return nil, err
+	}
+
+	select {
+	case secret := <-secretChannel:
+		return secret, nil
+	case <-time.After(waitForPodTimeOut):
+		return nil, errors.Errorf("waited %s but couldn't find secret '%s'", waitForPodTimeOut, name)
+	}
+}
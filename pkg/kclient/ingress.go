@@ -0,0 +1,160 @@
+package kclient
+
+import (
+	"github.com/pkg/errors"
+
+	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// IngressPath is a single HTTP path rule within an IngressRule, routing
+// requests under Path to the given service/port.
+type IngressPath struct {
+	// Path is the URL path to match, e.g. "/" or "/api".
+	Path string
+	// PathType controls how Path is matched. Defaults to
+	// extensionsv1.PathTypeImplementationSpecific when empty.
+	PathType extensionsv1.PathType
+	// ServiceName is the backend Service to route matching requests to.
+	ServiceName string
+	// ServicePort is the backend Service's port, by name or number.
+	ServicePort intstr.IntOrString
+}
+
+// IngressRule is a single host's routing rules, consisting of one or more
+// paths.
+type IngressRule struct {
+	// Host is the domain name this rule applies to. An empty Host matches
+	// any inbound host.
+	Host string
+	Paths []IngressPath
+}
+
+// IngressTLS configures HTTPS termination for one or more hosts using a
+// pre-existing, e.g. cert-manager-issued, TLS secret.
+type IngressTLS struct {
+	Hosts      []string
+	SecretName string
+}
+
+// IngressParams describes an Ingress in full: multiple host/path rules, TLS
+// termination and annotations, so that it can be used with real ingress
+// controllers (Traefik, NGINX, ...) instead of only the single-host,
+// single-path, no-TLS shape the original CreateIngress supported.
+type IngressParams struct {
+	Name   string
+	Labels map[string]string
+
+	Rules       []IngressRule
+	TLS         []IngressTLS
+	Annotations map[string]string
+
+	// IngressClassName selects the ingress controller that should implement
+	// this Ingress. Left unset, the cluster's default IngressClass is used.
+	IngressClassName *string
+}
+
+// toIngressSpec converts the backend-agnostic IngressParams into the
+// extensions/v1beta1 IngressSpec shape expected by the Kubernetes API.
+func toIngressSpec(params IngressParams) extensionsv1.IngressSpec {
+	spec := extensionsv1.IngressSpec{
+		IngressClassName: params.IngressClassName,
+	}
+
+	for _, rule := range params.Rules {
+		var paths []extensionsv1.HTTPIngressPath
+		for _, p := range rule.Paths {
+			pathType := p.PathType
+			httpPath := extensionsv1.HTTPIngressPath{
+				Path: p.Path,
+				Backend: extensionsv1.IngressBackend{
+					ServiceName: p.ServiceName,
+					ServicePort: p.ServicePort,
+				},
+			}
+			if pathType != "" {
+				httpPath.PathType = &pathType
+			}
+			paths = append(paths, httpPath)
+		}
+
+		spec.Rules = append(spec.Rules, extensionsv1.IngressRule{
+			Host: rule.Host,
+			IngressRuleValue: extensionsv1.IngressRuleValue{
+				HTTP: &extensionsv1.HTTPIngressRuleValue{
+					Paths: paths,
+				},
+			},
+		})
+	}
+
+	for _, tls := range params.TLS {
+		spec.TLS = append(spec.TLS, extensionsv1.IngressTLS{
+			Hosts:      tls.Hosts,
+			SecretName: tls.SecretName,
+		})
+	}
+
+	return spec
+}
+
+// CreateIngressWithParams creates an Ingress supporting multiple host/path
+// rules, TLS termination and annotations.
+func (c *Client) CreateIngressWithParams(params IngressParams) (*extensionsv1.Ingress, error) {
+	ingress := &extensionsv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        params.Name,
+			Labels:      params.Labels,
+			Annotations: params.Annotations,
+		},
+		Spec: toIngressSpec(params),
+	}
+
+	r, err := c.KubeClient.ExtensionsV1beta1().Ingresses(c.Namespace).Create(ingress)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating ingress")
+	}
+	return r, nil
+}
+
+// UpdateIngress updates an existing Ingress to match the given params,
+// fetching the current object first so its ResourceVersion is preserved.
+func (c *Client) UpdateIngress(params IngressParams) (*extensionsv1.Ingress, error) {
+	existing, err := c.KubeClient.ExtensionsV1beta1().Ingresses(c.Namespace).Get(params.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get ingress %s for update", params.Name)
+	}
+
+	existing.Labels = params.Labels
+	existing.Annotations = params.Annotations
+	existing.Spec = toIngressSpec(params)
+
+	r, err := c.KubeClient.ExtensionsV1beta1().Ingresses(c.Namespace).Update(existing)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to update ingress %s", params.Name)
+	}
+	return r, nil
+}
+
+// CreateIngress creates a single-host, single-path, non-TLS ingress. It is a
+// backward-compatible wrapper around CreateIngressWithParams for callers that
+// haven't been migrated to the richer API yet.
+func (c *Client) CreateIngress(name string, serviceName string, ingressDomain string, portNumber intstr.IntOrString, labels map[string]string) (*extensionsv1.Ingress, error) {
+	return c.CreateIngressWithParams(IngressParams{
+		Name:   name,
+		Labels: labels,
+		Rules: []IngressRule{
+			{
+				Host: ingressDomain,
+				Paths: []IngressPath{
+					{
+						Path:        "/",
+						ServiceName: serviceName,
+						ServicePort: portNumber,
+					},
+				},
+			},
+		},
+	})
+}
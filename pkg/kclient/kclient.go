@@ -2,6 +2,7 @@ package kclient
 
 import (
 	taro "archive/tar"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,31 +12,36 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 
-	"github.com/redhat-developer/odo-fork/pkg/log"
+	"github.com/redhat-developer/odo-fork/pkg/kclient/retry"
 	"github.com/redhat-developer/odo-fork/pkg/preference"
 	"github.com/redhat-developer/odo-fork/pkg/util"
 
 	// api resource types
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	extensionsv1 "k8s.io/api/extensions/v1beta1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/apimachinery/pkg/watch"
 
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
 )
@@ -68,6 +74,31 @@ type Client struct {
 	CoreV1Client v1.CoreV1Interface
 	KubeConfig   clientcmd.ClientConfig
 	Namespace    string
+
+	// DynamicClient and Mapper back ApplyManifest/DeleteManifest so that
+	// arbitrary (including CRD-defined) resources can be applied without a
+	// typed client for every kind. Both are set by New(); the Mapper resolves
+	// kinds through discovery lazily, on first use.
+	DynamicClient dynamic.Interface
+	Mapper        meta.RESTMapper
+
+	// plugins backs the ResourcePlugin registry used by RegisterPlugin/Plugin.
+	// It's built lazily via pluginsOnce so a Client is usable without it.
+	pluginsOnce sync.Once
+	plugins     *pluginRegistry
+
+	// sharedInformerFactory backs GetNamespaceNames, ListSecrets,
+	// ListIngresses, GetDeploymentsFromSelector, WaitAndGetPod and
+	// WaitAndGetSecret. It's built lazily via informerFactoryOnce, and
+	// started lazily (once) via informerStartOnce the first time Start is
+	// called, directly or through one of those methods. informerStopCh is
+	// created alongside the factory and owns its lifetime; it's never a
+	// per-call channel, since closing it would permanently stop the shared
+	// informers for every later caller.
+	informerFactoryOnce   sync.Once
+	informerStartOnce     sync.Once
+	sharedInformerFactory informers.SharedInformerFactory
+	informerStopCh        chan struct{}
 }
 
 // New creates a new client
@@ -90,6 +121,18 @@ func New(skipConnectionCheck bool) (*Client, error) {
 	}
 	client.KubeClient = kubeClient
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	client.DynamicClient = dynamicClient
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	client.Mapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
 	namespace, _, err := client.KubeConfig.Namespace()
 	if err != nil {
 		return nil, err
@@ -174,20 +217,6 @@ func (c *Client) GetCurrentNamespace() string {
 	return c.Namespace
 }
 
-// GetNamespaceNames return list of existing namespaces that user has access to.
-func (c *Client) GetNamespaceNames() ([]string, error) {
-	namespaces, err := c.KubeClient.CoreV1().Namespaces().List(metav1.ListOptions{})
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to list namespaces")
-	}
-
-	var namespaceNames []string
-	for _, p := range namespaces.Items {
-		namespaceNames = append(namespaceNames, p.Name)
-	}
-	return namespaceNames, nil
-}
-
 // GetNamespace returns namespace based on the name of the project.Errors related to
 // namespace not being found or forbidden are translated to nil project for compatibility
 func (c *Client) GetNamespace(namespace string) (*corev1.Namespace, error) {
@@ -209,7 +238,7 @@ func (c *Client) GetNamespace(namespace string) (*corev1.Namespace, error) {
 }
 
 // CreateNewNamespace creates namespace with given projectName
-func (c *Client) CreateNewNamespace(namespace string, wait bool) error {
+func (c *Client) CreateNewNamespace(ctx context.Context, namespace string, wait bool) error {
 	// Instantiate watcher before requesting new namespace
 	// If watched is created after the namespace it can lead to situation when the namespace is created before the watcher.
 	// When this happens, it gets stuck waiting for event that already happened.
@@ -224,10 +253,13 @@ func (c *Client) CreateNewNamespace(namespace string, wait bool) error {
 		defer watcher.Stop()
 	}
 
-	_, err := c.KubeClient.CoreV1().Namespaces().Create(&corev1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: namespace,
-		},
+	err := retry.CreateWithRetry(ctx, func() error {
+		_, err := c.KubeClient.CoreV1().Namespaces().Create(&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+			},
+		})
+		return err
 	})
 
 	if err != nil {
@@ -284,10 +316,15 @@ func addLabelsToArgs(labels map[string]string, args []string) []string {
 }
 
 // GetSecret returns the Secret object in the given namespace
-func (c *Client) GetSecret(name, namespace string) (*corev1.Secret, error) {
-	secret, err := c.KubeClient.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+func (c *Client) GetSecret(ctx context.Context, name, namespace string) (*corev1.Secret, error) {
+	var secret *corev1.Secret
+	err := retry.GetWithRetry(ctx, func() error {
+		var getErr error
+		secret, getErr = c.KubeClient.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+		return getErr
+	})
 	if err != nil {
-		return nil, errors.Wrapf(err, "unable to get the secret %s", secret)
+		return nil, errors.Wrapf(err, "unable to get the secret %s", name)
 	}
 	return secret, nil
 }
@@ -350,7 +387,7 @@ func deleteEnvVars(existingEnvs []corev1.EnvVar, envTobeDeleted string) []corev1
 // CreateService generates and creates the service
 // commonObjectMeta is the ObjectMeta for the service
 // dc is the deploymentConfig to get the container ports
-func (c *Client) CreateService(commonObjectMeta metav1.ObjectMeta, containerPorts []corev1.ContainerPort) (*corev1.Service, error) {
+func (c *Client) CreateService(ctx context.Context, commonObjectMeta metav1.ObjectMeta, containerPorts []corev1.ContainerPort) (*corev1.Service, error) {
 	// generate and create Service
 	var svcPorts []corev1.ServicePort
 	for _, containerPort := range containerPorts {
@@ -372,7 +409,13 @@ func (c *Client) CreateService(commonObjectMeta metav1.ObjectMeta, containerPort
 			},
 		},
 	}
-	createdSvc, err := c.KubeClient.CoreV1().Services(c.Namespace).Create(&svc)
+
+	var createdSvc *corev1.Service
+	err := retry.CreateWithRetry(ctx, func() error {
+		var createErr error
+		createdSvc, createErr = c.KubeClient.CoreV1().Services(c.Namespace).Create(&svc)
+		return createErr
+	})
 	if err != nil {
 		return nil, errors.Wrapf(err, "unable to create Service for %s", commonObjectMeta.Name)
 	}
@@ -381,104 +424,28 @@ func (c *Client) CreateService(commonObjectMeta metav1.ObjectMeta, containerPort
 
 // CreateSecret generates and creates the secret
 // commonObjectMeta is the ObjectMeta for the service
-func (c *Client) CreateSecret(objectMeta metav1.ObjectMeta, data map[string]string) error {
+func (c *Client) CreateSecret(ctx context.Context, objectMeta metav1.ObjectMeta, data map[string]string) error {
 
 	secret := corev1.Secret{
 		ObjectMeta: objectMeta,
 		Type:       corev1.SecretTypeOpaque,
 		StringData: data,
 	}
-	_, err := c.KubeClient.CoreV1().Secrets(c.Namespace).Create(&secret)
+	err := retry.CreateWithRetry(ctx, func() error {
+		_, createErr := c.KubeClient.CoreV1().Secrets(c.Namespace).Create(&secret)
+		return createErr
+	})
 	if err != nil {
 		return errors.Wrapf(err, "unable to create secret for %s", objectMeta.Name)
 	}
 	return nil
 }
 
-// WaitAndGetPod block and waits until pod matching selector is in in Running state
-// desiredPhase cannot be PodFailed or PodUnknown
-func (c *Client) WaitAndGetPod(selector string, desiredPhase corev1.PodPhase, waitMessage string) (*corev1.Pod, error) {
-	glog.V(4).Infof("Waiting for %s pod", selector)
-	s := log.Spinner(waitMessage)
-	defer s.End(false)
-
-	w, err := c.KubeClient.CoreV1().Pods(c.Namespace).Watch(metav1.ListOptions{
-		LabelSelector: selector,
-	})
-	if err != nil {
-		return nil, errors.Wrapf(err, "unable to watch pod")
-	}
-	defer w.Stop()
-
-	podChannel := make(chan *corev1.Pod)
-	watchErrorChannel := make(chan error)
-
-	go func() {
-	loop:
-		for {
-			val, ok := <-w.ResultChan()
-			if !ok {
-				watchErrorChannel <- errors.New("watch channel was closed")
-				break loop
-			}
-			if e, ok := val.Object.(*corev1.Pod); ok {
-				glog.V(4).Infof("Status of %s pod is %s", e.Name, e.Status.Phase)
-				switch e.Status.Phase {
-				case desiredPhase:
-					s.End(true)
-					glog.V(4).Infof("Pod %s is %v", e.Name, desiredPhase)
-					podChannel <- e
-					break loop
-				case corev1.PodFailed, corev1.PodUnknown:
-					watchErrorChannel <- errors.Errorf("pod %s status %s", e.Name, e.Status.Phase)
-					break loop
-				}
-			} else {
-				watchErrorChannel <- errors.New("unable to convert event object to Pod")
-				break loop
-			}
-		}
-		close(podChannel)
-		close(watchErrorChannel)
-	}()
-
-	select {
-	case val := <-podChannel:
-		return val, nil
-	case err := <-watchErrorChannel:
-		return nil, err
-	case <-time.After(waitForPodTimeOut):
-		return nil, errors.Errorf("waited %s but couldn't find running pod matching selector: '%s'", waitForPodTimeOut, selector)
-	}
-}
-
-// WaitAndGetSecret blocks and waits until the secret is available
-func (c *Client) WaitAndGetSecret(name string, namespace string) (*corev1.Secret, error) {
-	glog.V(4).Infof("Waiting for secret %s to become available", name)
-
-	w, err := c.KubeClient.CoreV1().Secrets(namespace).Watch(metav1.ListOptions{
-		FieldSelector: fields.Set{"metadata.name": name}.AsSelector().String(),
-	})
-	if err != nil {
-		return nil, errors.Wrapf(err, "unable to watch secret")
-	}
-	defer w.Stop()
-	for {
-		val, ok := <-w.ResultChan()
-		if !ok {
-			break
-		}
-		if e, ok := val.Object.(*corev1.Secret); ok {
-			glog.V(4).Infof("Secret %s now exists", e.Name)
-			return e, nil
-		}
-	}
-	return nil, errors.Errorf("unknown error while waiting for secret '%s'", name)
-}
-
 // DeleteNamespace deletes given namespace
-func (c *Client) DeleteNamespace(name string) error {
-	err := c.KubeClient.CoreV1().Namespaces().Delete(name, &metav1.DeleteOptions{})
+func (c *Client) DeleteNamespace(ctx context.Context, name string) error {
+	err := retry.DeleteWithRetry(ctx, func() error {
+		return c.KubeClient.CoreV1().Namespaces().Delete(name, &metav1.DeleteOptions{})
+	})
 	if err != nil {
 		return errors.Wrap(err, "unable to delete namespace")
 	}
@@ -566,81 +533,47 @@ func (c *Client) GetDeploymentLabelValues(label string, selector string) ([]stri
 	return values, nil
 }
 
-// Define a function that is meant to create patch based on the contents of the DC
-type depPatchProvider func(dc *appsv1.Deployment) (string, error)
-
-// LinkSecret links a secret to the Deployment of a component
-func (c *Client) LinkSecret(secretName, componentName, applicationName string) error {
-
-	var dcPatchProvider = func(dc *appsv1.Deployment) (string, error) {
-		if len(dc.Spec.Template.Spec.Containers[0].EnvFrom) > 0 {
-			// we always add the link as the first value in the envFrom array. That way we don't need to know the existing value
-			return fmt.Sprintf(`[{ "op": "add", "path": "/spec/template/spec/containers/0/envFrom/0", "value": {"secretRef": {"name": "%s"}} }]`, secretName), nil
-		}
-
-		//in this case we need to add the full envFrom value
-		return fmt.Sprintf(`[{ "op": "add", "path": "/spec/template/spec/containers/0/envFrom", "value": [{"secretRef": {"name": "%s"}}] }]`, secretName), nil
+// LinkSecret links a secret to the Deployment of a component via a
+// Server-Side Apply on the Deployment's primary container, so it can't
+// clobber a concurrent edit to a different container or a different
+// already-linked secret. See ApplySecretLink.
+func (c *Client) LinkSecret(ctx context.Context, secretName, componentName, applicationName string) error {
+	depName, err := util.NamespaceKubernetesObject(componentName, applicationName)
+	if err != nil {
+		return err
 	}
 
-	return c.patchDepOfComponent(componentName, applicationName, dcPatchProvider)
-}
-
-// UnlinkSecret unlinks a secret to the Deployment of a component
-func (c *Client) UnlinkSecret(secretName, componentName, applicationName string) error {
-	// Remove the Secret from the container
-	var dcPatchProvider = func(dc *appsv1.Deployment) (string, error) {
-		indexForRemoval := -1
-		for i, env := range dc.Spec.Template.Spec.Containers[0].EnvFrom {
-			if env.SecretRef.Name == secretName {
-				indexForRemoval = i
-				break
-			}
-		}
-
-		if indexForRemoval == -1 {
-			return "", fmt.Errorf("Deployment does not contain a link to %s", secretName)
-		}
-
-		return fmt.Sprintf(`[{"op": "remove", "path": "/spec/template/spec/containers/0/envFrom/%d"}]`, indexForRemoval), nil
+	dep, err := c.GetDeploymentsFromName(depName)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to locate Deployment for component %s of application %s", componentName, applicationName)
 	}
 
-	return c.patchDepOfComponent(componentName, applicationName, dcPatchProvider)
+	i, err := primaryContainerIndex(dep)
+	if err != nil {
+		return err
+	}
+	return c.ApplySecretLink(ctx, depName, dep.Spec.Template.Spec.Containers[i].Name, secretName)
 }
 
-// Define a function that is meant to create patch based on the contents of the DC
-type dcPatchProvider func(dc *appsv1.Deployment) (string, error)
-
-// this function will look up the appropriate Deployment, and execute the specified patch
-// the whole point of using patch is to avoid race conditions where we try to update
-// dc while it's being simultaneously updated from another source (for example Kubernetes itself)
-// this will result in the triggering of a redeployment
-func (c *Client) patchDepOfComponent(componentName, applicationName string, dcPatchProvider dcPatchProvider) error {
+// UnlinkSecret unlinks a secret from the Deployment of a component via a
+// Server-Side Apply on the Deployment's primary container. See
+// RemoveSecretLink.
+func (c *Client) UnlinkSecret(ctx context.Context, secretName, componentName, applicationName string) error {
 	depName, err := util.NamespaceKubernetesObject(componentName, applicationName)
 	if err != nil {
 		return err
 	}
 
-	dc, err := c.KubeClient.AppsV1().Deployments(c.Namespace).Get(depName, metav1.GetOptions{})
+	dep, err := c.GetDeploymentsFromName(depName)
 	if err != nil {
 		return errors.Wrapf(err, "Unable to locate Deployment for component %s of application %s", componentName, applicationName)
 	}
 
-	if dcPatchProvider != nil {
-		patch, err := dcPatchProvider(dc)
-		if err != nil {
-			return errors.Wrap(err, "Unable to create a patch for the Deployments")
-		}
-
-		// patch the DeploymentConfig with the secret
-		_, err = c.KubeClient.AppsV1().Deployments(c.Namespace).Patch(depName, types.JSONPatchType, []byte(patch))
-		if err != nil {
-			return errors.Wrapf(err, "Deployment not patched %s", dc.Name)
-		}
-	} else {
-		return errors.Wrapf(err, "dcPatch was not properly set")
+	i, err := primaryContainerIndex(dep)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return c.RemoveSecretLink(ctx, depName, dep.Spec.Template.Spec.Containers[i].Name, secretName)
 }
 
 // Service struct holds the service name and its corresponding list of plans
@@ -650,45 +583,6 @@ type Service struct {
 	PlanList []string
 }
 
-// CreateIngress creates an ingress object for the given service and with the given labels
-// serviceName is the name of the service for the target reference
-// ingressDomain is the ingress domain to use for the ingress
-// portNumber is the target port of the ingress
-func (c *Client) CreateIngress(name string, serviceName string, ingressDomain string, portNumber intstr.IntOrString, labels map[string]string) (*extensionsv1.Ingress, error) {
-	ingress := &extensionsv1.Ingress{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:   name,
-			Labels: labels,
-		},
-		Spec: extensionsv1.IngressSpec{
-			Rules: []extensionsv1.IngressRule{
-				{
-					Host: ingressDomain,
-					IngressRuleValue: extensionsv1.IngressRuleValue{
-						HTTP: &extensionsv1.HTTPIngressRuleValue{
-							Paths: []extensionsv1.HTTPIngressPath{
-								{
-									Path: "/",
-									Backend: extensionsv1.IngressBackend{
-										ServiceName: serviceName,
-										ServicePort: portNumber,
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	r, err := c.KubeClient.ExtensionsV1beta1().Ingresses(c.Namespace).Create(ingress)
-	if err != nil {
-		return nil, errors.Wrap(err, "error creating ingress")
-	}
-	return r, nil
-}
-
 // DeleteIngress deleted the given route
 func (c *Client) DeleteIngress(name string) error {
 	err := c.KubeClient.ExtensionsV1beta1().Ingresses(c.Namespace).Delete(name, &metav1.DeleteOptions{})
@@ -698,18 +592,6 @@ func (c *Client) DeleteIngress(name string) error {
 	return nil
 }
 
-// ListIngresses lists all the ingresses based on the given label selector
-func (c *Client) ListIngresses(labelSelector string) ([]extensionsv1.Ingress, error) {
-	routeList, err := c.KubeClient.ExtensionsV1beta1().Ingresses(c.Namespace).List(metav1.ListOptions{
-		LabelSelector: labelSelector,
-	})
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to get ingress list")
-	}
-
-	return routeList.Items, nil
-}
-
 // ListIngressNames lists all the names of the ingresses based on the given label
 // selector
 func (c *Client) ListIngressNames(labelSelector string) ([]string, error) {
@@ -726,43 +608,6 @@ func (c *Client) ListIngressNames(labelSelector string) ([]string, error) {
 	return routeNames, nil
 }
 
-// ListSecrets lists all the secrets based on the given label selector
-func (c *Client) ListSecrets(labelSelector string) ([]corev1.Secret, error) {
-	listOptions := metav1.ListOptions{}
-	if len(labelSelector) > 0 {
-		listOptions = metav1.ListOptions{
-			LabelSelector: labelSelector,
-		}
-	}
-
-	secretList, err := c.KubeClient.CoreV1().Secrets(c.Namespace).List(listOptions)
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to get secret list")
-	}
-
-	return secretList.Items, nil
-}
-
-// GetDeploymentsFromSelector returns an array of Deployment
-// resources which match the given selector
-func (c *Client) GetDeploymentsFromSelector(selector string) ([]appsv1.Deployment, error) {
-	var depList *appsv1.DeploymentList
-	var err error
-	if selector != "" {
-		depList, err = c.KubeClient.AppsV1().Deployments(c.Namespace).List(metav1.ListOptions{
-			LabelSelector: selector,
-		})
-	} else {
-		depList, err = c.KubeClient.AppsV1().Deployments(c.Namespace).List(metav1.ListOptions{
-			FieldSelector: fields.Set{"metadata.namespace": c.Namespace}.AsSelector().String(),
-		})
-	}
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to list Deployments")
-	}
-	return depList.Items, nil
-}
-
 // GetServicesFromSelector returns an array of Service resources which match the
 // given selector
 func (c *Client) GetServicesFromSelector(selector string) ([]corev1.Service, error) {
@@ -1091,18 +936,47 @@ func (c *Client) GetOneServiceFromSelector(selector string) (*corev1.Service, er
 	return &services[0], nil
 }
 
-// AddEnvironmentVariablesToDeployment adds the given environment
-// variables to the only container in the Deployment Config and updates in the
-// cluster
+// primaryContainerAnnotation names the container a devfile component treats
+// as its "main" one (where exec/watch operate by default) when a Deployment
+// has more than one, e.g. sidecars like istio-proxy or a log shipper
+// alongside the user's own container.
+const primaryContainerAnnotation = "odo.dev/primary-container"
+
+// primaryContainerIndex returns the index, within dep.Spec.Template.Spec.Containers,
+// of the container dep's primaryContainerAnnotation names. If the annotation
+// is absent, or names a container the Deployment doesn't have, it falls back
+// to the first container in the pod template - the same container
+// single-container components have always targeted, so existing components
+// keep working unannotated.
+func primaryContainerIndex(dep *appsv1.Deployment) (int, error) {
+	containers := dep.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return -1, fmt.Errorf("Deployment %v has no containers", dep.Name)
+	}
+
+	if name := dep.Annotations[primaryContainerAnnotation]; name != "" {
+		for i, container := range containers {
+			if container.Name == name {
+				return i, nil
+			}
+		}
+	}
+
+	return 0, nil
+}
+
+// AddEnvironmentVariablesToDeployment adds the given environment variables to
+// dep's primary container (see primaryContainerIndex) and updates the
+// Deployment in the cluster.
 func (c *Client) AddEnvironmentVariablesToDeployment(envs []corev1.EnvVar, dep *appsv1.Deployment) error {
-	numContainers := len(dep.Spec.Template.Spec.Containers)
-	if numContainers != 1 {
-		return fmt.Errorf("expected exactly one container in Deployment %v, got %v", dep.Name, numContainers)
+	i, err := primaryContainerIndex(dep)
+	if err != nil {
+		return err
 	}
 
-	dep.Spec.Template.Spec.Containers[0].Env = append(dep.Spec.Template.Spec.Containers[0].Env, envs...)
+	dep.Spec.Template.Spec.Containers[i].Env = append(dep.Spec.Template.Spec.Containers[i].Env, envs...)
 
-	_, err := c.KubeClient.AppsV1().Deployments(c.Namespace).Update(dep)
+	_, err = c.KubeClient.AppsV1().Deployments(c.Namespace).Update(dep)
 	if err != nil {
 		return errors.Wrapf(err, "unable to update Deployment %v", dep.Name)
 	}
@@ -1188,13 +1062,16 @@ func (c *Client) ExecCMDInContainer(podName string, cmd []string, stdout io.Writ
 	return nil
 }
 
-// GetVolumeMountsFromDC returns a list of all volume mounts in the given Deployment
+// GetVolumeMountsFromDC returns the volume mounts of the given Deployment's
+// primary container (see primaryContainerIndex) - merging every container's
+// mounts together would silently attribute a sidecar's mounts to the
+// component's own container once a Deployment has more than one.
 func (c *Client) GetVolumeMountsFromDC(dep *appsv1.Deployment) []corev1.VolumeMount {
-	var volumeMounts []corev1.VolumeMount
-	for _, container := range dep.Spec.Template.Spec.Containers {
-		volumeMounts = append(volumeMounts, container.VolumeMounts...)
+	i, err := primaryContainerIndex(dep)
+	if err != nil {
+		return nil
 	}
-	return volumeMounts
+	return dep.Spec.Template.Spec.Containers[i].VolumeMounts
 }
 
 // IsVolumeAnEmptyDir returns true if the volume is an EmptyDir, false if not
@@ -1268,7 +1145,8 @@ func GetInputEnvVarsFromStrings(envVars []string) ([]corev1.EnvVar, error) {
 	return inputEnvVars, nil
 }
 
-// GetEnvVarsFromDep retrieves the env vars from the DC
+// GetEnvVarsFromDep retrieves the env vars from dcName's primary container
+// (see primaryContainerIndex).
 // dcName is the name of the dc from which the env vars are retrieved
 // projectName is the name of the project
 func (c *Client) GetEnvVarsFromDep(dcName string) ([]corev1.EnvVar, error) {
@@ -1277,10 +1155,10 @@ func (c *Client) GetEnvVarsFromDep(dcName string) ([]corev1.EnvVar, error) {
 		return nil, errors.Wrap(err, "error occurred while retrieving the dc")
 	}
 
-	numContainers := len(dc.Spec.Template.Spec.Containers)
-	if numContainers != 1 {
-		return nil, fmt.Errorf("expected exactly one container in Deployment Config %v, got %v", dc.Name, numContainers)
+	i, err := primaryContainerIndex(dc)
+	if err != nil {
+		return nil, err
 	}
 
-	return dc.Spec.Template.Spec.Containers[0].Env, nil
+	return dc.Spec.Template.Spec.Containers[i].Env, nil
 }
\ No newline at end of file
@@ -0,0 +1,254 @@
+package kclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ResourcePlugin is the CRUD contract a resource kind must implement to be
+// managed through Client's plugin registry. Built-in plugins are registered
+// for "deployment", "service", "namespace", "secret" and "ingress"; callers
+// can register additional kinds (e.g. OpenShift Routes or CRD-defined
+// resources) with RegisterPlugin without patching this package.
+type ResourcePlugin interface {
+	// Create decodes spec (YAML/JSON) into the plugin's resource type and
+	// creates it, returning the created object's name.
+	Create(ctx context.Context, spec []byte) (string, error)
+	// Get returns the named resource.
+	Get(ctx context.Context, name string) (interface{}, error)
+	// Delete deletes the named resource.
+	Delete(ctx context.Context, name string) error
+	// List returns the names of resources matching selector.
+	List(ctx context.Context, selector string) ([]string, error)
+}
+
+// pluginRegistry holds the kind -> ResourcePlugin mapping for a Client. It is
+// built lazily so that Client values constructed without New() (e.g. in
+// tests, against a fake clientset) still get the built-in plugins.
+type pluginRegistry struct {
+	mu      sync.RWMutex
+	plugins map[string]ResourcePlugin
+}
+
+func (c *Client) registry() *pluginRegistry {
+	c.pluginsOnce.Do(func() {
+		c.plugins = &pluginRegistry{plugins: map[string]ResourcePlugin{}}
+		c.plugins.plugins["deployment"] = &deploymentPlugin{client: c}
+		c.plugins.plugins["service"] = &servicePlugin{client: c}
+		c.plugins.plugins["namespace"] = &namespacePlugin{client: c}
+		c.plugins.plugins["secret"] = &secretPlugin{client: c}
+		c.plugins.plugins["ingress"] = &ingressPlugin{client: c}
+	})
+	return c.plugins
+}
+
+// RegisterPlugin registers (or replaces) the ResourcePlugin responsible for
+// the given resource kind, e.g. "route" or a CRD's plural resource name.
+func (c *Client) RegisterPlugin(kind string, p ResourcePlugin) {
+	registry := c.registry()
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.plugins[kind] = p
+}
+
+// Plugin returns the ResourcePlugin registered for kind, or an error if none
+// is registered.
+func (c *Client) Plugin(kind string) (ResourcePlugin, error) {
+	registry := c.registry()
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	p, ok := registry.plugins[kind]
+	if !ok {
+		return nil, fmt.Errorf("no resource plugin registered for kind %q", kind)
+	}
+	return p, nil
+}
+
+// deploymentPlugin wraps the typed AppsV1().Deployments() client.
+type deploymentPlugin struct {
+	client *Client
+}
+
+func (p *deploymentPlugin) Create(ctx context.Context, spec []byte) (string, error) {
+	var dep appsv1.Deployment
+	if err := yaml.Unmarshal(spec, &dep); err != nil {
+		return "", errors.Wrap(err, "unable to decode Deployment spec")
+	}
+	created, err := p.client.KubeClient.AppsV1().Deployments(p.client.Namespace).Create(&dep)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to create Deployment %s", dep.Name)
+	}
+	return created.Name, nil
+}
+
+func (p *deploymentPlugin) Get(ctx context.Context, name string) (interface{}, error) {
+	return p.client.KubeClient.AppsV1().Deployments(p.client.Namespace).Get(name, metav1.GetOptions{})
+}
+
+func (p *deploymentPlugin) Delete(ctx context.Context, name string) error {
+	return p.client.KubeClient.AppsV1().Deployments(p.client.Namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+func (p *deploymentPlugin) List(ctx context.Context, selector string) ([]string, error) {
+	list, err := p.client.KubeClient.AppsV1().Deployments(p.client.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list Deployments")
+	}
+	var names []string
+	for _, d := range list.Items {
+		names = append(names, d.Name)
+	}
+	return names, nil
+}
+
+// servicePlugin wraps the typed CoreV1().Services() client.
+type servicePlugin struct {
+	client *Client
+}
+
+func (p *servicePlugin) Create(ctx context.Context, spec []byte) (string, error) {
+	var svc corev1.Service
+	if err := yaml.Unmarshal(spec, &svc); err != nil {
+		return "", errors.Wrap(err, "unable to decode Service spec")
+	}
+	created, err := p.client.KubeClient.CoreV1().Services(p.client.Namespace).Create(&svc)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to create Service %s", svc.Name)
+	}
+	return created.Name, nil
+}
+
+func (p *servicePlugin) Get(ctx context.Context, name string) (interface{}, error) {
+	return p.client.KubeClient.CoreV1().Services(p.client.Namespace).Get(name, metav1.GetOptions{})
+}
+
+func (p *servicePlugin) Delete(ctx context.Context, name string) error {
+	return p.client.KubeClient.CoreV1().Services(p.client.Namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+func (p *servicePlugin) List(ctx context.Context, selector string) ([]string, error) {
+	list, err := p.client.KubeClient.CoreV1().Services(p.client.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list Services")
+	}
+	var names []string
+	for _, s := range list.Items {
+		names = append(names, s.Name)
+	}
+	return names, nil
+}
+
+// namespacePlugin wraps the typed CoreV1().Namespaces() client. List and
+// Delete are cluster-scoped, so selector/name behave the same regardless of
+// client.Namespace.
+type namespacePlugin struct {
+	client *Client
+}
+
+func (p *namespacePlugin) Create(ctx context.Context, spec []byte) (string, error) {
+	var ns corev1.Namespace
+	if err := yaml.Unmarshal(spec, &ns); err != nil {
+		return "", errors.Wrap(err, "unable to decode Namespace spec")
+	}
+	created, err := p.client.KubeClient.CoreV1().Namespaces().Create(&ns)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to create Namespace %s", ns.Name)
+	}
+	return created.Name, nil
+}
+
+func (p *namespacePlugin) Get(ctx context.Context, name string) (interface{}, error) {
+	return p.client.KubeClient.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+}
+
+func (p *namespacePlugin) Delete(ctx context.Context, name string) error {
+	return p.client.KubeClient.CoreV1().Namespaces().Delete(name, &metav1.DeleteOptions{})
+}
+
+func (p *namespacePlugin) List(ctx context.Context, selector string) ([]string, error) {
+	list, err := p.client.KubeClient.CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list Namespaces")
+	}
+	var names []string
+	for _, n := range list.Items {
+		names = append(names, n.Name)
+	}
+	return names, nil
+}
+
+// secretPlugin wraps the typed CoreV1().Secrets() client.
+type secretPlugin struct {
+	client *Client
+}
+
+func (p *secretPlugin) Create(ctx context.Context, spec []byte) (string, error) {
+	var secret corev1.Secret
+	if err := yaml.Unmarshal(spec, &secret); err != nil {
+		return "", errors.Wrap(err, "unable to decode Secret spec")
+	}
+	created, err := p.client.KubeClient.CoreV1().Secrets(p.client.Namespace).Create(&secret)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to create secret for %s", secret.Name)
+	}
+	return created.Name, nil
+}
+
+func (p *secretPlugin) Get(ctx context.Context, name string) (interface{}, error) {
+	return p.client.KubeClient.CoreV1().Secrets(p.client.Namespace).Get(name, metav1.GetOptions{})
+}
+
+func (p *secretPlugin) Delete(ctx context.Context, name string) error {
+	return p.client.KubeClient.CoreV1().Secrets(p.client.Namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+func (p *secretPlugin) List(ctx context.Context, selector string) ([]string, error) {
+	secrets, err := p.client.ListSecrets(selector)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, s := range secrets {
+		names = append(names, s.Name)
+	}
+	return names, nil
+}
+
+// ingressPlugin wraps the typed ExtensionsV1beta1().Ingresses() client.
+type ingressPlugin struct {
+	client *Client
+}
+
+func (p *ingressPlugin) Create(ctx context.Context, spec []byte) (string, error) {
+	var ingress extensionsv1.Ingress
+	if err := yaml.Unmarshal(spec, &ingress); err != nil {
+		return "", errors.Wrap(err, "unable to decode Ingress spec")
+	}
+	created, err := p.client.KubeClient.ExtensionsV1beta1().Ingresses(p.client.Namespace).Create(&ingress)
+	if err != nil {
+		return "", errors.Wrap(err, "error creating ingress")
+	}
+	return created.Name, nil
+}
+
+func (p *ingressPlugin) Get(ctx context.Context, name string) (interface{}, error) {
+	return p.client.KubeClient.ExtensionsV1beta1().Ingresses(p.client.Namespace).Get(name, metav1.GetOptions{})
+}
+
+func (p *ingressPlugin) Delete(ctx context.Context, name string) error {
+	return p.client.DeleteIngress(name)
+}
+
+func (p *ingressPlugin) List(ctx context.Context, selector string) ([]string, error) {
+	return p.client.ListIngressNames(selector)
+}
@@ -0,0 +1,149 @@
+package kclient
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// pluginTestCases exercises Create/Get/List/Delete for every built-in
+// ResourcePlugin against a fake clientset, so adding a new plugin without
+// giving it the same CRUD coverage as its siblings is easy to notice.
+var pluginTestCases = []struct {
+	kind string
+	spec string
+}{
+	{
+		kind: "deployment",
+		spec: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-deployment
+  labels:
+    app: my-app
+spec:
+  selector:
+    matchLabels:
+      app: my-app
+  template:
+    metadata:
+      labels:
+        app: my-app
+    spec:
+      containers:
+      - name: main
+        image: my-image
+`,
+	},
+	{
+		kind: "service",
+		spec: `apiVersion: v1
+kind: Service
+metadata:
+  name: my-service
+  labels:
+    app: my-app
+spec:
+  ports:
+  - port: 8080
+`,
+	},
+	{
+		kind: "namespace",
+		spec: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: my-namespace
+  labels:
+    app: my-app
+`,
+	},
+	{
+		kind: "secret",
+		spec: `apiVersion: v1
+kind: Secret
+metadata:
+  name: my-secret
+  labels:
+    app: my-app
+`,
+	},
+	{
+		kind: "ingress",
+		spec: `apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: my-ingress
+  labels:
+    app: my-app
+`,
+	},
+}
+
+func TestResourcePlugins(t *testing.T) {
+	for _, tt := range pluginTestCases {
+		t.Run(tt.kind, func(t *testing.T) {
+			client := &Client{KubeClient: fake.NewSimpleClientset(), Namespace: "my-project"}
+
+			plugin, err := client.Plugin(tt.kind)
+			if err != nil {
+				t.Fatalf("Plugin(%q) returned error: %v", tt.kind, err)
+			}
+
+			ctx := context.Background()
+
+			name, err := plugin.Create(ctx, []byte(tt.spec))
+			if err != nil {
+				t.Fatalf("Create() returned error: %v", err)
+			}
+			if name == "" {
+				t.Fatal("Create() returned an empty name")
+			}
+
+			if _, err := plugin.Get(ctx, name); err != nil {
+				t.Fatalf("Get(%q) returned error: %v", name, err)
+			}
+
+			// ListSecrets/ListIngresses are served from the shared informers,
+			// which only see what was Created above once started and synced.
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			if err := client.Start(stopCh); err != nil {
+				t.Fatalf("Start() returned error: %v", err)
+			}
+
+			names, err := plugin.List(ctx, "app=my-app")
+			if err != nil {
+				t.Fatalf("List() returned error: %v", err)
+			}
+			if len(names) != 1 || names[0] != name {
+				t.Fatalf("List() = %v, want [%s]", names, name)
+			}
+
+			if err := plugin.Delete(ctx, name); err != nil {
+				t.Fatalf("Delete(%q) returned error: %v", name, err)
+			}
+			if _, err := plugin.Get(ctx, name); err == nil {
+				t.Fatalf("Get(%q) succeeded after Delete, want error", name)
+			}
+		})
+	}
+}
+
+func TestPluginUnregisteredKind(t *testing.T) {
+	client := &Client{KubeClient: fake.NewSimpleClientset()}
+
+	if _, err := client.Plugin("route"); err == nil {
+		t.Fatal("Plugin(\"route\") succeeded, want error for an unregistered kind")
+	}
+}
+
+func TestRegisterPlugin(t *testing.T) {
+	client := &Client{KubeClient: fake.NewSimpleClientset()}
+	client.RegisterPlugin("deployment", &deploymentPlugin{client: client})
+
+	if _, err := client.Plugin("deployment"); err != nil {
+		t.Fatalf("Plugin(\"deployment\") returned error after RegisterPlugin: %v", err)
+	}
+}
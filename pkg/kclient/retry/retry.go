@@ -0,0 +1,97 @@
+// Package retry provides context-aware wrappers around Kubernetes client-go
+// CRUD calls that back off and retry on transient API server errors
+// (server timeouts, rate limiting, internal errors and connection resets),
+// and refresh-and-retry update conflicts via client-go's RetryOnConflict.
+// It exists so that long CI runs and `odo push` inner loops stop failing
+// outright on hiccups that a second attempt would have sailed through.
+package retry
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// backoff is used by every *WithRetry helper; it makes up to 5 attempts in
+// all (the first call plus 4 retries) with exponentially increasing delay,
+// and no new attempt starts once the caller's context is done.
+var backoff = wait.Backoff{
+	Duration: 200 * time.Millisecond,
+	Factor:   2.0,
+	Steps:    5,
+}
+
+// isRetryable reports whether err is a transient condition worth retrying:
+// server timeouts, throttling, internal errors, or a dropped connection.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if kerrors.IsServerTimeout(err) || kerrors.IsTooManyRequests(err) || kerrors.IsInternalError(err) {
+		return true
+	}
+	if netErr, ok := errors.Cause(err).(net.Error); ok {
+		return netErr.Timeout() || netErr.Temporary()
+	}
+	return false
+}
+
+// withRetry runs fn, retrying on transient errors per backoff, and bails out
+// early if ctx is done.
+func withRetry(ctx context.Context, fn func() error) error {
+	var lastErr error
+	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		lastErr = fn()
+		if lastErr == nil {
+			return true, nil
+		}
+		if !isRetryable(lastErr) {
+			return false, lastErr
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return lastErr
+	}
+	return err
+}
+
+// CreateWithRetry calls createFunc, retrying on transient API server errors.
+func CreateWithRetry(ctx context.Context, createFunc func() error) error {
+	return withRetry(ctx, createFunc)
+}
+
+// GetWithRetry calls getFunc, retrying on transient API server errors.
+func GetWithRetry(ctx context.Context, getFunc func() error) error {
+	return withRetry(ctx, getFunc)
+}
+
+// DeleteWithRetry calls deleteFunc, retrying on transient API server errors.
+// A "not found" error from deleteFunc is treated as success, since the end
+// state the caller wants (the object gone) already holds.
+func DeleteWithRetry(ctx context.Context, deleteFunc func() error) error {
+	err := withRetry(ctx, deleteFunc)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// UpdateWithRetry calls updateFunc, retrying both on transient API server
+// errors and, via client-go's RetryOnConflict, on update conflicts - where
+// updateFunc is expected to re-fetch the latest object before retrying a
+// conflicting update.
+func UpdateWithRetry(ctx context.Context, updateFunc func() error) error {
+	return withRetry(ctx, func() error {
+		return retry.RetryOnConflict(retry.DefaultRetry, updateFunc)
+	})
+}
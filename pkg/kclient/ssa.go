@@ -0,0 +1,141 @@
+package kclient
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// deploymentResource is the GroupVersionResource ApplyDeployment patches
+// through c.DynamicClient - the typed AppsV1().Deployments() client's Patch
+// predates metav1.PatchOptions (its signature is just
+// Patch(name, pt, data, subresources...)), so Server-Side Apply, which needs
+// to set Force, has to go through the dynamic client instead.
+var deploymentResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+// fieldManager identifies odo-fork as the owner of the fields it
+// Server-Side-Applies, so repeated applies of the same intent don't conflict
+// with themselves, while conflicting with other actors (e.g. a controller)
+// is still reported rather than silently overwritten.
+const fieldManager = "odo-fork"
+
+// forceOwnership lets an apply take ownership of fields currently owned by
+// another manager. We always want this: our intent (e.g. "this secret is
+// linked") should win over a stale apply from a previous odo-fork version.
+var forceOwnership = true
+
+// ApplyDeployment Server-Side-Applies patch - which must only set the fields
+// that should change - onto the Deployment named name. Only the fields
+// present in patch are touched; everything else about the live object (and
+// fields owned by other managers) is left alone.
+func (c *Client) ApplyDeployment(ctx context.Context, name string, patch *appsv1.Deployment) error {
+	patch.TypeMeta = metav1.TypeMeta{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+	}
+	patch.Name = name
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal apply patch for Deployment")
+	}
+
+	_, err = c.DynamicClient.Resource(deploymentResource).Namespace(c.Namespace).Patch(
+		name,
+		types.ApplyPatchType,
+		data,
+		metav1.PatchOptions{FieldManager: fieldManager, Force: &forceOwnership},
+	)
+	if err != nil {
+		return errors.Wrapf(err, "unable to apply Deployment %s", name)
+	}
+	return nil
+}
+
+// deploymentWithContainer builds the minimal Deployment object needed to
+// Server-Side-Apply a single named container's envFrom: only the container's
+// name (its merge key) and envFrom are carried over from container.
+func deploymentWithContainer(containerName string, container corev1.Container) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: containerName, EnvFrom: container.EnvFrom}},
+				},
+			},
+		},
+	}
+}
+
+// ApplySecretLink links secretName into the named container of deploymentName
+// by Server-Side-Applying only that container's envFrom, so concurrent edits
+// to other containers or other envFrom entries aren't clobbered. The secret
+// is re-linked (not duplicated) if it's already present.
+func (c *Client) ApplySecretLink(ctx context.Context, deploymentName, containerName, secretName string) error {
+	dep, err := c.GetDeploymentsFromName(deploymentName)
+	if err != nil {
+		return errors.Wrapf(err, "unable to locate Deployment %s", deploymentName)
+	}
+
+	container, err := FindContainer(dep.Spec.Template.Spec.Containers, containerName)
+	if err != nil {
+		return errors.Wrapf(err, "unable to locate container %s in Deployment %s", containerName, deploymentName)
+	}
+
+	envFrom := container.EnvFrom
+	alreadyLinked := false
+	for _, ef := range envFrom {
+		if ef.SecretRef != nil && ef.SecretRef.Name == secretName {
+			alreadyLinked = true
+			break
+		}
+	}
+	if !alreadyLinked {
+		envFrom = append(envFrom, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+			},
+		})
+	}
+	container.EnvFrom = envFrom
+
+	return c.ApplyDeployment(ctx, deploymentName, deploymentWithContainer(containerName, container))
+}
+
+// RemoveSecretLink unlinks secretName from the named container of
+// deploymentName by Server-Side-Applying the container's envFrom with that
+// secret's entry removed.
+func (c *Client) RemoveSecretLink(ctx context.Context, deploymentName, containerName, secretName string) error {
+	dep, err := c.GetDeploymentsFromName(deploymentName)
+	if err != nil {
+		return errors.Wrapf(err, "unable to locate Deployment %s", deploymentName)
+	}
+
+	container, err := FindContainer(dep.Spec.Template.Spec.Containers, containerName)
+	if err != nil {
+		return errors.Wrapf(err, "unable to locate container %s in Deployment %s", containerName, deploymentName)
+	}
+
+	var envFrom []corev1.EnvFromSource
+	found := false
+	for _, ef := range container.EnvFrom {
+		if ef.SecretRef != nil && ef.SecretRef.Name == secretName {
+			found = true
+			continue
+		}
+		envFrom = append(envFrom, ef)
+	}
+	if !found {
+		return errors.Errorf("container %s of Deployment %s does not contain a link to %s", containerName, deploymentName, secretName)
+	}
+	container.EnvFrom = envFrom
+
+	return c.ApplyDeployment(ctx, deploymentName, deploymentWithContainer(containerName, container))
+}
@@ -0,0 +1,332 @@
+package occlient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// defaultCacheResyncPeriod is how often the informer cache relists against
+// the API server to repair any watch events it might have missed, absent an
+// explicit CacheOptions.ResyncPeriod.
+const defaultCacheResyncPeriod = 10 * time.Minute
+
+// CacheOptions configures the ResourceCache behind the selector-query
+// methods (GetDeploymentsFromSelector and friends).
+type CacheOptions struct {
+	// NoCache bypasses the informer cache entirely and issues a direct List
+	// call per query - for one-shot CLI invocations where starting and
+	// syncing an informer costs more than the handful of API calls it would
+	// save.
+	NoCache bool
+	// ResyncPeriod overrides defaultCacheResyncPeriod.
+	ResyncPeriod time.Duration
+}
+
+// ResourceCache answers selector queries from a namespace-scoped
+// SharedInformerFactory's local indexers instead of hitting the API server
+// on every call.
+type ResourceCache struct {
+	factory informers.SharedInformerFactory
+	synced  bool
+}
+
+// cache lazily builds and starts c's ResourceCache, honoring CacheOptions.NoCache.
+func (c *Client) cache() (*ResourceCache, error) {
+	if c.CacheOptions.NoCache {
+		return nil, nil
+	}
+
+	var err error
+	c.cacheOnce.Do(func() {
+		resync := c.CacheOptions.ResyncPeriod
+		if resync == 0 {
+			resync = defaultCacheResyncPeriod
+		}
+
+		factory := informers.NewSharedInformerFactoryWithOptions(c.KubeClient, resync, informers.WithNamespace(c.Namespace))
+		// Touching each informer registers it with the factory so Start()
+		// below actually runs it.
+		factory.Apps().V1().Deployments().Informer()
+		factory.Core().V1().Services().Informer()
+		factory.Core().V1().Pods().Informer()
+		factory.Core().V1().PersistentVolumeClaims().Informer()
+
+		stopCh := make(chan struct{})
+		factory.Start(stopCh)
+		synced := factory.WaitForCacheSync(stopCh)
+		for _, ok := range synced {
+			if !ok {
+				err = errors.New("unable to sync informer cache")
+				return
+			}
+		}
+
+		c.resourceCache = &ResourceCache{factory: factory, synced: true}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.resourceCache, nil
+}
+
+func metaListOptions(selector string) metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: selector}
+}
+
+func parseSelector(selector string) (labels.Selector, error) {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse selector %q", selector)
+	}
+	return sel, nil
+}
+
+// GetDeploymentsFromSelector returns the Deployments in c.Namespace matching
+// selector, answered from the informer cache unless CacheOptions.NoCache is set.
+func (c *Client) GetDeploymentsFromSelector(selector string) ([]appsv1.Deployment, error) {
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := c.cache()
+	if err != nil {
+		return nil, err
+	}
+	if rc == nil {
+		list, err := c.KubeClient.AppsV1().Deployments(c.Namespace).List(metaListOptions(selector))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to list Deployments")
+		}
+		return list.Items, nil
+	}
+
+	deployments, err := rc.factory.Apps().V1().Deployments().Lister().Deployments(c.Namespace).List(sel)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list Deployments from cache")
+	}
+	result := make([]appsv1.Deployment, 0, len(deployments))
+	for _, d := range deployments {
+		result = append(result, *d)
+	}
+	return result, nil
+}
+
+// GetServicesFromSelector returns the Services in c.Namespace matching selector.
+func (c *Client) GetServicesFromSelector(selector string) ([]corev1.Service, error) {
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := c.cache()
+	if err != nil {
+		return nil, err
+	}
+	if rc == nil {
+		list, err := c.KubeClient.CoreV1().Services(c.Namespace).List(metaListOptions(selector))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to list Services")
+		}
+		return list.Items, nil
+	}
+
+	services, err := rc.factory.Core().V1().Services().Lister().Services(c.Namespace).List(sel)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list Services from cache")
+	}
+	result := make([]corev1.Service, 0, len(services))
+	for _, s := range services {
+		result = append(result, *s)
+	}
+	return result, nil
+}
+
+// GetPVCsFromSelector returns the PersistentVolumeClaims in c.Namespace
+// matching selector.
+func (c *Client) GetPVCsFromSelector(selector string) ([]corev1.PersistentVolumeClaim, error) {
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := c.cache()
+	if err != nil {
+		return nil, err
+	}
+	if rc == nil {
+		list, err := c.KubeClient.CoreV1().PersistentVolumeClaims(c.Namespace).List(metaListOptions(selector))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to list PersistentVolumeClaims")
+		}
+		return list.Items, nil
+	}
+
+	pvcs, err := rc.factory.Core().V1().PersistentVolumeClaims().Lister().PersistentVolumeClaims(c.Namespace).List(sel)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list PersistentVolumeClaims from cache")
+	}
+	result := make([]corev1.PersistentVolumeClaim, 0, len(pvcs))
+	for _, p := range pvcs {
+		result = append(result, *p)
+	}
+	return result, nil
+}
+
+// GetOneDeploymentFromSelector returns the single Deployment in c.Namespace
+// matching selector, erroring if there isn't exactly one match.
+func (c *Client) GetOneDeploymentFromSelector(selector string) (*appsv1.Deployment, error) {
+	deployments, err := c.GetDeploymentsFromSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	switch len(deployments) {
+	case 0:
+		return nil, errors.Errorf("no Deployment found for selector %q", selector)
+	case 1:
+		return &deployments[0], nil
+	default:
+		return nil, errors.Errorf("multiple Deployments found for selector %q, expected one", selector)
+	}
+}
+
+// GetOnePodFromSelector returns the single Pod in c.Namespace matching
+// selector, erroring if there isn't exactly one match.
+func (c *Client) GetOnePodFromSelector(selector string) (*corev1.Pod, error) {
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := c.cache()
+	if err != nil {
+		return nil, err
+	}
+
+	var pods []*corev1.Pod
+	if rc == nil {
+		list, err := c.KubeClient.CoreV1().Pods(c.Namespace).List(metaListOptions(selector))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to list Pods")
+		}
+		for i := range list.Items {
+			pods = append(pods, &list.Items[i])
+		}
+	} else {
+		pods, err = rc.factory.Core().V1().Pods().Lister().Pods(c.Namespace).List(sel)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to list Pods from cache")
+		}
+	}
+
+	switch len(pods) {
+	case 0:
+		return nil, errors.Errorf("no Pod found for selector %q", selector)
+	case 1:
+		return pods[0], nil
+	default:
+		return nil, errors.Errorf("multiple Pods found for selector %q, expected one", selector)
+	}
+}
+
+// EventType is the kind of change WatchComponent reports.
+type EventType string
+
+const (
+	// EventAdded means a new Pod matching the watched selector appeared.
+	EventAdded EventType = "Added"
+	// EventModified means a watched Pod was updated, e.g. restarted.
+	EventModified EventType = "Modified"
+	// EventDeleted means a watched Pod was removed.
+	EventDeleted EventType = "Deleted"
+)
+
+// Event is a single change to a Pod matching the selector passed to
+// WatchComponent.
+type Event struct {
+	Type EventType
+	Pod  *corev1.Pod
+}
+
+// WatchComponent returns a channel of Add/Modified/Deleted events for Pods in
+// c.Namespace matching selector, so callers like "odo watch"/"odo push" can
+// react to pod restarts without polling GetOnePodFromSelector in a loop. The
+// channel is closed when stopCh is closed.
+func (c *Client) WatchComponent(selector string, stopCh <-chan struct{}) (<-chan Event, error) {
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := c.cache()
+	if err != nil {
+		return nil, err
+	}
+	if rc == nil {
+		return nil, errors.New("WatchComponent requires the informer cache; it is not available with CacheOptions.NoCache set")
+	}
+
+	events := make(chan Event)
+	matches := func(obj interface{}) (*corev1.Pod, bool) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || !sel.Matches(labels.Set(pod.Labels)) {
+			return nil, false
+		}
+		return pod, true
+	}
+
+	// send and wg together make closing events safe: wg tracks every send
+	// that's in flight (blocked on either events or stopCh), so the closing
+	// goroutine below can wait for all of them to finish - after it has also
+	// unregistered the handler so no new ones start - before it closes the
+	// channel no send can still be racing it.
+	var wg sync.WaitGroup
+	send := func(ev Event) {
+		wg.Add(1)
+		defer wg.Done()
+		select {
+		case events <- ev:
+		case <-stopCh:
+		}
+	}
+
+	informer := rc.factory.Core().V1().Pods().Informer()
+	reg, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := matches(obj); ok {
+				send(Event{Type: EventAdded, Pod: pod})
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if pod, ok := matches(newObj); ok {
+				send(Event{Type: EventModified, Pod: pod})
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if pod, ok := matches(obj); ok {
+				send(Event{Type: EventDeleted, Pod: pod})
+			}
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to subscribe to pod informer")
+	}
+
+	go func() {
+		<-stopCh
+		informer.RemoveEventHandler(reg)
+		wg.Wait()
+		close(events)
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,183 @@
+package occlient
+
+import (
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// primaryContainerAnnotation names the container a devfile component treats
+// as its "main" one (where odo exec/odo watch target by default) when a
+// Deployment has more than one, e.g. sidecars like istio-proxy or a log
+// shipper alongside the user's own container.
+const primaryContainerAnnotation = "odo.dev/primary-container"
+
+// ContainerRef names a single container within a named Deployment, the unit
+// the container-scoped helpers below (AddEnvironmentVariablesToContainer,
+// GetEnvVarsFromContainer, GetVolumeMountsFromContainer, CopyFileToContainer)
+// operate on.
+type ContainerRef struct {
+	DeploymentName string
+	ContainerName  string
+}
+
+// PrimaryContainer returns the container dep's primaryContainerAnnotation
+// names. If the annotation is absent, or names a container the Deployment
+// doesn't have, it falls back to the first container in the pod template -
+// the same container single-container devfiles have always targeted, so
+// existing components keep working unannotated.
+func PrimaryContainer(dep *appsv1.Deployment) (corev1.Container, error) {
+	return primaryContainer(dep.Annotations, dep.Spec.Template.Spec.Containers, dep.Name)
+}
+
+// primaryContainer is the shared resolution PrimaryContainer and
+// resolveContainerName build on: it applies primaryContainerAnnotation
+// against containers, falling back to the first one.
+func primaryContainer(annotations map[string]string, containers []corev1.Container, ownerName string) (corev1.Container, error) {
+	if len(containers) == 0 {
+		return corev1.Container{}, errors.Errorf("%v has no containers", ownerName)
+	}
+
+	if name := annotations[primaryContainerAnnotation]; name != "" {
+		for _, container := range containers {
+			if container.Name == name {
+				return container, nil
+			}
+		}
+	}
+
+	return containers[0], nil
+}
+
+// resolveContainerName returns containerName unchanged if it's set. If it's
+// empty, it fetches podName and resolves its PrimaryContainer - a Pod's
+// annotations and containers are copied from its owning Deployment's pod
+// template, so primaryContainerAnnotation is visible here too. This is what
+// lets ExecCMDInContainer, ExecWithResize and CopyFile target the right
+// container of a multi-container pod without every caller having to know
+// about primaryContainerAnnotation itself.
+func (c *Client) resolveContainerName(podName, containerName string) (string, error) {
+	if containerName != "" {
+		return containerName, nil
+	}
+
+	pod, err := c.KubeClient.CoreV1().Pods(c.Namespace).Get(podName, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to get Pod %s", podName)
+	}
+
+	container, err := primaryContainer(pod.Annotations, pod.Spec.Containers, pod.Name)
+	if err != nil {
+		return "", err
+	}
+	return container.Name, nil
+}
+
+// getDeployment fetches name directly from the API server - the
+// container-scoped helpers below mutate a single container in place, so
+// unlike the selector queries in cache.go they always need the latest
+// ResourceVersion rather than a possibly-stale cached copy.
+func (c *Client) getDeployment(name string) (*appsv1.Deployment, error) {
+	dep, err := c.KubeClient.AppsV1().Deployments(c.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get Deployment %s", name)
+	}
+	return dep, nil
+}
+
+// findContainerIndex returns the index of the named container within containers.
+func findContainerIndex(containers []corev1.Container, name string) (int, error) {
+	for i, container := range containers {
+		if container.Name == name {
+			return i, nil
+		}
+	}
+	return -1, errors.Errorf("unable to find container %q", name)
+}
+
+// AddEnvironmentVariablesToContainer adds envs to ref's container and
+// updates the Deployment in the cluster, the container-scoped counterpart of
+// AddEnvironmentVariablesToDeployment for Deployments with more than one
+// container.
+func (c *Client) AddEnvironmentVariablesToContainer(ref ContainerRef, envs []corev1.EnvVar) error {
+	dep, err := c.getDeployment(ref.DeploymentName)
+	if err != nil {
+		return err
+	}
+
+	i, err := findContainerIndex(dep.Spec.Template.Spec.Containers, ref.ContainerName)
+	if err != nil {
+		return err
+	}
+	dep.Spec.Template.Spec.Containers[i].Env = append(dep.Spec.Template.Spec.Containers[i].Env, envs...)
+
+	if _, err := c.KubeClient.AppsV1().Deployments(c.Namespace).Update(dep); err != nil {
+		return errors.Wrapf(err, "unable to update Deployment %v", dep.Name)
+	}
+	return nil
+}
+
+// GetEnvVarsFromContainer retrieves the env vars from ref's container.
+func (c *Client) GetEnvVarsFromContainer(ref ContainerRef) ([]corev1.EnvVar, error) {
+	dep, err := c.getDeployment(ref.DeploymentName)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := findContainerIndex(dep.Spec.Template.Spec.Containers, ref.ContainerName)
+	if err != nil {
+		return nil, err
+	}
+	return dep.Spec.Template.Spec.Containers[i].Env, nil
+}
+
+// GetVolumeMountsFromContainer returns the volume mounts of ref's container,
+// the container-scoped counterpart of GetVolumeMountsFromDC (which always
+// returns the primary container's mounts).
+func (c *Client) GetVolumeMountsFromContainer(ref ContainerRef) ([]corev1.VolumeMount, error) {
+	dep, err := c.getDeployment(ref.DeploymentName)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := findContainerIndex(dep.Spec.Template.Spec.Containers, ref.ContainerName)
+	if err != nil {
+		return nil, err
+	}
+	return dep.Spec.Template.Spec.Containers[i].VolumeMounts, nil
+}
+
+// podForDeployment returns the name of a running Pod backed by dep, found via
+// the Deployment's own pod selector.
+func (c *Client) podForDeployment(dep *appsv1.Deployment) (string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(dep.Spec.Selector)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to read pod selector for Deployment %s", dep.Name)
+	}
+
+	pod, err := c.GetOnePodFromSelector(selector.String())
+	if err != nil {
+		return "", err
+	}
+	return pod.Name, nil
+}
+
+// CopyFileToContainer copies the local file or directory at localPath into
+// targetPath inside ref's container, finding the pod that currently backs
+// ref.DeploymentName first - the container-scoped counterpart of CopyFile
+// for Deployments with more than one container.
+func (c *Client) CopyFileToContainer(ref ContainerRef, localPath, targetPath string) error {
+	dep, err := c.getDeployment(ref.DeploymentName)
+	if err != nil {
+		return err
+	}
+
+	podName, err := c.podForDeployment(dep)
+	if err != nil {
+		return err
+	}
+
+	return c.CopyFile(localPath, podName, ref.ContainerName, targetPath)
+}
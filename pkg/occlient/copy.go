@@ -0,0 +1,167 @@
+package occlient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IDPair is a uid/gid pair used to chown files written by CopyToContainer,
+// mirroring the shape podman's archive copy API uses for --chown.
+type IDPair struct {
+	UID int
+	GID int
+}
+
+// CopyOptions configures CopyToContainer's archive/extract behavior.
+type CopyOptions struct {
+	// Extract auto-untars a tar stream written to the destination, the way
+	// `docker cp`/`podman cp` do, instead of writing it as a literal file.
+	Extract bool
+	// Chown, if set, changes the ownership of copied files/directories.
+	Chown *IDPair
+	// NoOverwriteDirNonDir, with Extract, passes --keep-directory-symlink to
+	// tar, so an existing symlink to a directory is kept rather than replaced
+	// by a directory from the archive. The copy is not refused, other
+	// directory/non-directory replacements are not checked, and the option
+	// has no effect without Extract.
+	NoOverwriteDirNonDir bool
+	// PreserveMode keeps the archived files' mode bits instead of applying
+	// the container's umask. It only applies with Extract.
+	PreserveMode bool
+}
+
+// FileInfo describes a path inside a container, as reported by
+// StatInContainer.
+type FileInfo struct {
+	Name       string
+	Size       int64
+	Mode       os.FileMode
+	IsDir      bool
+	IsSymlink  bool
+	LinkTarget string
+}
+
+// CopyFromContainer streams srcPath out of container as a tar archive. The
+// caller is responsible for closing the returned ReadCloser.
+func (c *Client) CopyFromContainer(podName, container, srcPath string) (io.ReadCloser, error) {
+	reader, writer := io.Pipe()
+
+	cmdArr := []string{"tar", "cf", "-", "-C", filepath.ToSlash(filepath.Dir(srcPath)), filepath.Base(srcPath)}
+
+	go func() {
+		err := c.execInContainer(podName, container, cmdArr, writer, nil, nil, false)
+		writer.CloseWithError(err)
+	}()
+
+	return reader, nil
+}
+
+// CopyToContainer streams src (expected to be a tar archive when
+// opts.Extract is set, or a literal file otherwise) into destPath inside
+// container.
+func (c *Client) CopyToContainer(podName, container, destPath string, src io.Reader, opts CopyOptions) error {
+	destPath = filepath.ToSlash(destPath)
+
+	var cmdArr []string
+	if opts.Extract {
+		cmdArr = []string{"tar", "xf", "-", "-C", destPath}
+		if opts.NoOverwriteDirNonDir {
+			cmdArr = append(cmdArr, "--keep-directory-symlink")
+		}
+		if opts.PreserveMode {
+			cmdArr = append(cmdArr, "--same-permissions")
+		} else {
+			cmdArr = append(cmdArr, "--no-same-permissions")
+		}
+	} else {
+		cmdArr = []string{"cp", "/dev/stdin", destPath}
+	}
+
+	if err := c.execInContainer(podName, container, cmdArr, nil, nil, src, false); err != nil {
+		return errors.Wrapf(err, "unable to copy to %s:%s", podName, destPath)
+	}
+
+	if opts.Chown != nil {
+		chownCmd := []string{"chown", "-R", fmt.Sprintf("%d:%d", opts.Chown.UID, opts.Chown.GID), destPath}
+		if err := c.execInContainer(podName, container, chownCmd, nil, nil, nil, false); err != nil {
+			return errors.Wrapf(err, "unable to chown %s:%s", podName, destPath)
+		}
+	}
+
+	return nil
+}
+
+// StatInContainer returns type/size/mode/link-target information about path
+// inside container, by exec'ing `stat` (and `readlink` for symlinks) in the
+// pod.
+func (c *Client) StatInContainer(podName, container, path string) (*FileInfo, error) {
+	// "%f %s %n" => hex file type+mode, size, name. GNU coreutils stat format.
+	var stdout bytes.Buffer
+	cmdArr := []string{"stat", "-c", "%f %s %n", path}
+	if err := c.execInContainer(podName, container, cmdArr, &stdout, nil, nil, false); err != nil {
+		return nil, errors.Wrapf(err, "unable to stat %s in pod %s", path, podName)
+	}
+
+	fields := strings.Fields(strings.TrimSpace(stdout.String()))
+	if len(fields) < 2 {
+		return nil, errors.Errorf("unexpected stat output for %s: %q", path, stdout.String())
+	}
+
+	rawMode, err := strconv.ParseUint(fields[0], 16, 32)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse stat mode for %s", path)
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse stat size for %s", path)
+	}
+
+	info := &FileInfo{
+		Name: filepath.Base(path),
+		Size: size,
+		Mode: os.FileMode(rawMode & 0777),
+	}
+
+	// The high nibble of %f is the POSIX file type: 4 = directory, a = symlink.
+	switch rawMode & 0170000 {
+	case 0040000:
+		info.IsDir = true
+	case 0120000:
+		info.IsSymlink = true
+		var linkOut bytes.Buffer
+		if err := c.execInContainer(podName, container, []string{"readlink", path}, &linkOut, nil, nil, false); err != nil {
+			return nil, errors.Wrapf(err, "unable to read link target of %s", path)
+		}
+		info.LinkTarget = strings.TrimSpace(linkOut.String())
+	}
+
+	return info, nil
+}
+
+// execInContainer is ExecCMDInContainer without the primary-container
+// resolution: container is passed to the exec subresource request as is,
+// even when it is empty.
+func (c *Client) execInContainer(podName, container string, cmd []string, stdout, stderr io.Writer, stdin io.Reader, tty bool) error {
+	exec, err := c.executor()
+	if err != nil {
+		return err
+	}
+
+	return exec.Exec(context.Background(), ExecOptions{
+		PodName:       podName,
+		ContainerName: container,
+		Command:       cmd,
+		Stdin:         stdin,
+		Stdout:        stdout,
+		Stderr:        stderr,
+		TTY:           tty,
+	})
+}
@@ -0,0 +1,275 @@
+package occlient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+
+	corev1 "k8s.io/api/core/v1"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport"
+)
+
+// TerminalSize is a single resize event, propagated down ExecWithResize's
+// resize channel so an interactive session can forward e.g. SIGWINCH.
+type TerminalSize remotecommand.TerminalSize
+
+// sizeQueue adapts a <-chan TerminalSize to the remotecommand.TerminalSizeQueue
+// interface the SPDY stream expects.
+type sizeQueue struct {
+	resize <-chan TerminalSize
+}
+
+func (q *sizeQueue) Next() *remotecommand.TerminalSize {
+	size, ok := <-q.resize
+	if !ok {
+		return nil
+	}
+	ts := remotecommand.TerminalSize(size)
+	return &ts
+}
+
+// ExecOptions holds everything needed to run a command in a container,
+// shared by every Executor backend.
+type ExecOptions struct {
+	PodName       string
+	ContainerName string
+	Command       []string
+	Stdin         io.Reader
+	Stdout        io.Writer
+	Stderr        io.Writer
+	TTY           bool
+}
+
+// Executor runs a command inside a container and streams its stdio over
+// whatever transport it implements.
+type Executor interface {
+	Exec(ctx context.Context, opts ExecOptions) error
+	ExecWithResize(ctx context.Context, opts ExecOptions, resize <-chan TerminalSize) error
+}
+
+// NewExecutor builds an Executor against restClient (typically
+// c.KubeClient.CoreV1().RESTClient()) in namespace, preferring the SPDY
+// (HTTP/1.1 upgrade) transport and falling back to a hand-rolled WebSocket
+// "v4.channel.k8s.io" client when SPDY's upgrade is refused - which happens
+// behind proxies/ingress controllers that strip the Upgrade header. The
+// fallback is hand-rolled rather than client-go's remotecommand.WebSocketExecutor
+// because that type was only added well after the client-go generation this
+// package otherwise targets (no context.Context-taking CRUD calls, typed
+// extensions/v1beta1 Ingress client); golang.org/x/net/websocket is the same
+// library kubectl's own exec fallback was built on in that era.
+func NewExecutor(config *restclient.Config, restClient restclient.Interface, namespace string) Executor {
+	return &negotiatingExecutor{config: config, restClient: restClient, namespace: namespace}
+}
+
+// negotiatingExecutor tries the SPDY backend first and transparently retries
+// over WebSocket if the server refuses the upgrade.
+type negotiatingExecutor struct {
+	config     *restclient.Config
+	restClient restclient.Interface
+	namespace  string
+}
+
+func (e *negotiatingExecutor) req(opts ExecOptions) *restclient.Request {
+	return e.restClient.Post().
+		Namespace(e.namespace).
+		Resource("pods").
+		Name(opts.PodName).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: opts.ContainerName,
+			Command:   opts.Command,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    opts.Stdout != nil,
+			Stderr:    opts.Stderr != nil,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+}
+
+func (e *negotiatingExecutor) Exec(ctx context.Context, opts ExecOptions) error {
+	return e.ExecWithResize(ctx, opts, nil)
+}
+
+func (e *negotiatingExecutor) ExecWithResize(ctx context.Context, opts ExecOptions, resize <-chan TerminalSize) error {
+	req := e.req(opts)
+
+	streamOpts := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Stderr: opts.Stderr,
+		Tty:    opts.TTY,
+	}
+	if resize != nil {
+		streamOpts.TerminalSizeQueue = &sizeQueue{resize: resize}
+	}
+
+	spdyExec, err := remotecommand.NewSPDYExecutor(e.config, "POST", req.URL())
+	if err == nil {
+		if streamErr := spdyExec.Stream(streamOpts); streamErr == nil {
+			return nil
+		} else if !isUpgradeRefused(streamErr) {
+			return errors.Wrap(streamErr, "error while streaming command over SPDY")
+		}
+	}
+
+	if err := e.streamWebSocket(req, opts, resize); err != nil {
+		return errors.Wrap(err, "error while streaming command over WebSocket")
+	}
+	return nil
+}
+
+// websocketProtocol is the subprotocol the apiserver's exec/attach endpoints
+// speak, multiplexing stdin/stdout/stderr/error/resize over one connection
+// via a single leading channel byte per frame.
+const websocketProtocol = "v4.channel.k8s.io"
+
+const (
+	wsChannelStdin = iota
+	wsChannelStdout
+	wsChannelStderr
+	wsChannelError
+	wsChannelResize
+)
+
+// streamWebSocket execs req over a raw WebSocket connection using the
+// v4.channel.k8s.io framing, for apiserver-fronting proxies that refuse the
+// SPDY upgrade. It's deliberately minimal: no reconnection, and the error
+// channel's payload is surfaced as a plain string rather than decoded as the
+// structured metav1.Status the apiserver actually sends.
+func (e *negotiatingExecutor) streamWebSocket(req *restclient.Request, opts ExecOptions, resize <-chan TerminalSize) error {
+	wsURL := *req.URL()
+	if wsURL.Scheme == "https" {
+		wsURL.Scheme = "wss"
+	} else {
+		wsURL.Scheme = "ws"
+	}
+
+	tlsConfig, err := transport.TLSConfigFor(e.config)
+	if err != nil {
+		return errors.Wrap(err, "unable to build TLS config")
+	}
+
+	wsConfig, err := websocket.NewConfig(wsURL.String(), "http://localhost")
+	if err != nil {
+		return errors.Wrap(err, "unable to build WebSocket config")
+	}
+	wsConfig.Protocol = []string{websocketProtocol}
+	wsConfig.TlsConfig = tlsConfig
+	if e.config.BearerToken != "" {
+		wsConfig.Header.Set("Authorization", "Bearer "+e.config.BearerToken)
+	}
+
+	conn, err := websocket.DialConfig(wsConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to dial WebSocket exec connection")
+	}
+	defer conn.Close()
+
+	if opts.Stdin != nil {
+		go copyToWebSocket(conn, wsChannelStdin, opts.Stdin)
+	}
+	if resize != nil {
+		go resizeToWebSocket(conn, resize)
+	}
+
+	errCh := make(chan error, 1)
+	for {
+		var frame []byte
+		if err := websocket.Message.Receive(conn, &frame); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if len(frame) == 0 {
+			continue
+		}
+
+		channel, payload := frame[0], frame[1:]
+		switch channel {
+		case wsChannelStdout:
+			if opts.Stdout != nil {
+				opts.Stdout.Write(payload)
+			}
+		case wsChannelStderr:
+			if opts.Stderr != nil {
+				opts.Stderr.Write(payload)
+			}
+		case wsChannelError:
+			if len(payload) > 0 {
+				errCh <- errors.Errorf("remote command failed: %s", payload)
+			}
+		}
+	}
+
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return nil
+	}
+}
+
+func copyToWebSocket(conn *websocket.Conn, channel byte, src io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			frame := append([]byte{channel}, buf[:n]...)
+			if werr := websocket.Message.Send(conn, frame); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func resizeToWebSocket(conn *websocket.Conn, resize <-chan TerminalSize) {
+	for size := range resize {
+		payload, err := json.Marshal(struct {
+			Width  uint16
+			Height uint16
+		}{size.Width, size.Height})
+		if err != nil {
+			continue
+		}
+		frame := append([]byte{wsChannelResize}, payload...)
+		if websocket.Message.Send(conn, frame) != nil {
+			return
+		}
+	}
+}
+
+// isUpgradeRefused reports whether err looks like the apiserver (or a proxy
+// in front of it) refused the HTTP upgrade SPDY needs, the case
+// negotiatingExecutor falls back to WebSocket for.
+func isUpgradeRefused(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "unable to upgrade connection") ||
+		strings.Contains(msg, "101 Switching Protocols") ||
+		strings.Contains(msg, "http: invalid Upgrade")
+}
+
+// CopyFileStream copies src into destPath inside the pod with a single exec
+// through executor. Each call opens its own exec stream, with its own
+// SPDY/WebSocket handshake; no connection is kept open or reused across
+// calls.
+func (c *Client) CopyFileStream(ctx context.Context, executor Executor, podName, containerName, destPath string, src io.Reader) error {
+	return executor.Exec(ctx, ExecOptions{
+		PodName:       podName,
+		ContainerName: containerName,
+		Command:       []string{"cp", "/dev/stdin", destPath},
+		Stdin:         src,
+	})
+}
@@ -0,0 +1,191 @@
+// Package occlient is the original, REST/exec-oriented Kubernetes client used
+// by odo-fork to interact with component pods: copying files in and out,
+// running commands, and the other pod-level plumbing that pkg/kclient (which
+// focuses on managing Kubernetes resources themselves) doesn't cover.
+package occlient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Client wraps the pieces of the Kubernetes API needed to interact with a
+// running component's pod(s): copying files, executing commands, and
+// streaming their output.
+type Client struct {
+	KubeClient kubernetes.Interface
+	KubeConfig clientcmd.ClientConfig
+	Namespace  string
+
+	// CacheOptions configures the informer-backed ResourceCache behind the
+	// selector-query methods (GetDeploymentsFromSelector and friends).
+	// NoCache is checked on every call; ResyncPeriod is only read when the
+	// cache is built, on the first call that uses it.
+	CacheOptions CacheOptions
+
+	cacheOnce     sync.Once
+	resourceCache *ResourceCache
+}
+
+// New creates a new Client using the user's current Kubernetes context.
+func New() (*Client, error) {
+	var client Client
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	configOverrides := &clientcmd.ConfigOverrides{}
+	client.KubeConfig = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
+
+	config, err := client.KubeConfig.ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to build Kubernetes client config")
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	client.KubeClient = kubeClient
+
+	namespace, _, err := client.KubeConfig.Namespace()
+	if err != nil {
+		return nil, err
+	}
+	client.Namespace = namespace
+
+	return &client, nil
+}
+
+// executor lazily builds the Executor ExecCMDInContainer and friends stream
+// commands through, negotiating SPDY vs. WebSocket per NewExecutor.
+func (c *Client) executor() (Executor, error) {
+	config, err := c.KubeConfig.ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get Kubernetes client config")
+	}
+	return NewExecutor(config, c.KubeClient.CoreV1().RESTClient(), c.Namespace), nil
+}
+
+// ExecCMDInContainer executes cmd in containerName of podName. An empty
+// containerName resolves to podName's PrimaryContainer, so single-container
+// pods keep working unannotated and multi-container ones target the right
+// container instead of whatever the apiserver would've guessed.
+func (c *Client) ExecCMDInContainer(podName, containerName string, cmd []string, stdout io.Writer, stderr io.Writer, stdin io.Reader, tty bool) error {
+	containerName, err := c.resolveContainerName(podName, containerName)
+	if err != nil {
+		return err
+	}
+
+	exec, err := c.executor()
+	if err != nil {
+		return err
+	}
+
+	return exec.Exec(context.Background(), ExecOptions{
+		PodName:       podName,
+		ContainerName: containerName,
+		Command:       cmd,
+		Stdin:         stdin,
+		Stdout:        stdout,
+		Stderr:        stderr,
+		TTY:           tty,
+	})
+}
+
+// ExecWithResize execs cmd in podName/containerName like ExecCMDInContainer,
+// additionally forwarding terminal resize events from resize for the
+// duration of the session - used for interactive TTY sessions where the
+// local terminal can change size mid-command.
+func (c *Client) ExecWithResize(ctx context.Context, podName, containerName string, cmd []string, stdout io.Writer, stderr io.Writer, stdin io.Reader, resize <-chan TerminalSize) error {
+	containerName, err := c.resolveContainerName(podName, containerName)
+	if err != nil {
+		return err
+	}
+
+	exec, err := c.executor()
+	if err != nil {
+		return err
+	}
+
+	return exec.ExecWithResize(ctx, ExecOptions{
+		PodName:       podName,
+		ContainerName: containerName,
+		Command:       cmd,
+		Stdin:         stdin,
+		Stdout:        stdout,
+		Stderr:        stderr,
+		TTY:           true,
+	}, resize)
+}
+
+// CopyFile copies the local file or directory at localPath into targetPath
+// inside containerName of targetPodName, using the default PackOptions. It
+// only copies host -> pod; see CopyFromContainer/CopyToContainer for the
+// bidirectional, archive/extract-aware API.
+func (c *Client) CopyFile(localPath, targetPodName, containerName, targetPath string) error {
+	return c.CopyFileWithOptions(localPath, targetPodName, containerName, targetPath, PackOptions{})
+}
+
+// CopyFileWithOptions is CopyFile with explicit control over compression,
+// ignore files, chunk size and deterministic headers - see PackOptions. When
+// opts.Compress is set but the target container's tar doesn't support "-z",
+// it's silently disabled for this call.
+func (c *Client) CopyFileWithOptions(localPath, targetPodName, containerName, targetPath string, opts PackOptions) error {
+	targetPath = filepath.ToSlash(targetPath)
+	// Entries are named relative to targetPath (just basename(localPath) and
+	// its contents), not prefixed with targetPath itself, so extracting with
+	// a plain "-C targetPath" lands them in the right place regardless of
+	// how many segments targetPath has - a hardcoded "--strip 1" only
+	// cancels out correctly when targetPath happens to be a single segment.
+	dest := filepath.ToSlash(filepath.Base(localPath))
+
+	if opts.Compress && !c.tarSupportsGzip(targetPodName, containerName) {
+		glog.V(4).Infof("container's tar does not support -z, falling back to uncompressed transfer")
+		opts.Compress = false
+	}
+
+	packer, err := NewPacker(localPath, opts)
+	if err != nil {
+		return errors.Wrap(err, "unable to build archive")
+	}
+
+	chunks, err := packer.Chunks(dest)
+	if err != nil {
+		return errors.Wrap(err, "unable to pack files for copy")
+	}
+
+	glog.V(4).Infof("CopyFile arguments: localPath %s, dest %s, %d chunk(s)", localPath, dest, len(chunks))
+
+	extractCmd := []string{"tar", "xf", "-", "-C", targetPath}
+	if opts.Compress {
+		extractCmd = []string{"tar", "xzf", "-", "-C", targetPath}
+	}
+
+	// Chunks are extracted with successive "tar xf -" invocations rather
+	// than one long-lived stream, so a single large push doesn't hold one
+	// exec connection open for minutes.
+	for _, chunk := range chunks {
+		if err := c.ExecCMDInContainer(targetPodName, containerName, extractCmd, nil, nil, bytes.NewReader(chunk), false); err != nil {
+			return errors.Wrapf(err, "unable to extract archive chunk into %s:%s", targetPodName, targetPath)
+		}
+	}
+	return nil
+}
+
+// tarSupportsGzip checks whether containerName's tar binary runs at all, as
+// a proxy for gzip ("-z") support - distinguishing GNU tar (which always
+// supports -z) from a busybox tar built without zlib would need per-distro
+// version parsing that isn't worth carrying, so a failing "tar --version" is
+// the only case this treats as unsupported.
+func (c *Client) tarSupportsGzip(podName, containerName string) bool {
+	return c.ExecCMDInContainer(podName, containerName, []string{"tar", "--version"}, ioutil.Discard, ioutil.Discard, nil, false) == nil
+}
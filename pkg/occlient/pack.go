@@ -0,0 +1,352 @@
+package occlient
+
+import (
+	taro "archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultMaxChunkSize is the archive size CopyFile splits a transfer at,
+// absent an explicit PackOptions.MaxChunkSize - past this a single "tar xf -"
+// exec stream would sit open for minutes on a large push.
+const defaultMaxChunkSize = 32 * 1024 * 1024
+
+// defaultIgnoreFiles are the ignore files Packer layers, in increasing
+// precedence: a later file's rules, and later lines within a file, win.
+var defaultIgnoreFiles = []string{".gitignore", ".dockerignore", ".odoignore"}
+
+// PackOptions configures how Packer builds the archive CopyFile streams into
+// a pod.
+type PackOptions struct {
+	// Compress gzips the archive. CopyFileWithOptions checks on each call
+	// that the target container's tar runs ("tar --version") and falls back
+	// to an uncompressed archive when it doesn't; whether that tar was built
+	// with gzip support is not checked.
+	Compress bool
+	// IgnoreFiles lists the ignore files to layer, relative to the root
+	// being packed, read in order. Defaults to defaultIgnoreFiles.
+	IgnoreFiles []string
+	// MaxChunkSize is the uncompressed archive size Packer splits a transfer
+	// at. Defaults to defaultMaxChunkSize.
+	MaxChunkSize int64
+	// Deterministic zeroes timestamps and normalizes mode bits so packing
+	// the same tree twice produces a byte-identical archive, for change
+	// detection that compares archives rather than walking the tree again.
+	Deterministic bool
+}
+
+func (o PackOptions) withDefaults() PackOptions {
+	if o.IgnoreFiles == nil {
+		o.IgnoreFiles = defaultIgnoreFiles
+	}
+	if o.MaxChunkSize <= 0 {
+		o.MaxChunkSize = defaultMaxChunkSize
+	}
+	return o
+}
+
+// ignorePattern is a single compiled line from an ignore file.
+type ignorePattern struct {
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	segments []string
+}
+
+func compileIgnoreLine(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, " \t")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	pat := ignorePattern{}
+	if strings.HasPrefix(line, "!") {
+		pat.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		pat.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if strings.HasPrefix(line, "/") {
+		pat.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+	pat.segments = strings.Split(line, "/")
+	if len(pat.segments) > 1 {
+		pat.anchored = true
+	}
+	return pat, true
+}
+
+// matches reports whether pat matches relPath (slash-separated, relative to
+// the ignore file's root).
+func (pat ignorePattern) matches(relPath string, isDir bool) bool {
+	if pat.dirOnly && !isDir {
+		return false
+	}
+
+	parts := strings.Split(relPath, "/")
+
+	if pat.anchored {
+		if len(parts) < len(pat.segments) {
+			return false
+		}
+		return segmentsMatch(pat.segments, parts[:len(pat.segments)])
+	}
+
+	// Unanchored single-segment patterns (e.g. "*.log", "node_modules") may
+	// match any path component, mirroring gitignore's directory-prefix rule.
+	for i := range parts {
+		if segmentsMatch(pat.segments, parts[i:i+1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func segmentsMatch(pattern, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i, seg := range pattern {
+		ok, err := filepath.Match(seg, path[i])
+		if err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ignoreMatcher is the compiled, layered result of defaultIgnoreFiles (or
+// PackOptions.IgnoreFiles), applied in file order with last-match-wins
+// semantics within and across files - the same precedence git itself uses.
+type ignoreMatcher struct {
+	patterns []ignorePattern
+}
+
+func loadIgnoreMatcher(root string, ignoreFiles []string) (*ignoreMatcher, error) {
+	m := &ignoreMatcher{}
+	for _, name := range ignoreFiles {
+		data, err := ioutil.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "unable to read %s", name)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if pat, ok := compileIgnoreLine(line); ok {
+				m.patterns = append(m.patterns, pat)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Ignored reports whether relPath (slash-separated, relative to the packed
+// root) should be excluded from the archive.
+func (m *ignoreMatcher) Ignored(relPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	ignored := false
+	for _, pat := range m.patterns {
+		if pat.matches(relPath, isDir) {
+			ignored = !pat.negate
+		}
+	}
+	return ignored
+}
+
+// Packer builds tar archives of a local directory tree for CopyFile,
+// honoring a layered ignoreMatcher and optionally producing deterministic,
+// size-bounded chunks.
+type Packer struct {
+	root    string
+	opts    PackOptions
+	matcher *ignoreMatcher
+}
+
+// NewPacker builds a Packer rooted at root, loading opts.IgnoreFiles (or
+// defaultIgnoreFiles) from root.
+func NewPacker(root string, opts PackOptions) (*Packer, error) {
+	opts = opts.withDefaults()
+	matcher, err := loadIgnoreMatcher(root, opts.IgnoreFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Packer{root: root, opts: opts, matcher: matcher}, nil
+}
+
+// packEntry is a single file or directory queued for archiving, resolved
+// ahead of writing so chunks can be split on a running size total.
+type packEntry struct {
+	srcPath  string
+	destPath string
+	info     os.FileInfo
+}
+
+// Chunks walks p.root (a file or directory) and returns its contents as one
+// or more tar archives, entries named as if p.root were relocated to
+// destPath. A chunk is closed once the file data written to it reaches
+// p.opts.MaxChunkSize, so a chunk can exceed that size by up to one file plus
+// its tar headers; files are never split across chunks. Chunks are
+// gzip-compressed when p.opts.Compress is set.
+func (p *Packer) Chunks(destPath string) ([][]byte, error) {
+	entries, err := p.collect(filepath.Clean(p.root), filepath.ToSlash(filepath.Clean(destPath)), "")
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks [][]byte
+	var buf *bytes.Buffer
+	var tw *taro.Writer
+	var size int64
+
+	flush := func() error {
+		if tw == nil {
+			return nil
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		chunks = append(chunks, buf.Bytes())
+		tw, buf = nil, nil
+		size = 0
+		return nil
+	}
+
+	for _, entry := range entries {
+		if tw == nil {
+			buf = &bytes.Buffer{}
+			tw = taro.NewWriter(buf)
+		}
+
+		hdr, err := taro.FileInfoHeader(entry.info, entry.srcPath)
+		if err != nil {
+			return nil, err
+		}
+		hdr.Name = entry.destPath
+		if p.opts.Deterministic {
+			normalizeHeader(hdr)
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if !entry.info.IsDir() && entry.info.Mode()&os.ModeSymlink == 0 {
+			n, err := writeFileBody(tw, entry.srcPath)
+			if err != nil {
+				return nil, err
+			}
+			size += n
+		}
+
+		if size >= p.opts.MaxChunkSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	if p.opts.Compress {
+		for i, chunk := range chunks {
+			gzipped, err := gzipBytes(chunk)
+			if err != nil {
+				return nil, err
+			}
+			chunks[i] = gzipped
+		}
+	}
+
+	return chunks, nil
+}
+
+// collect walks srcPath depth-first with sorted directory entries (so two
+// runs over an identical tree visit files in the same order, a prerequisite
+// for Deterministic's byte-identical archives), skipping anything
+// p.matcher.Ignored relative to p.root.
+func (p *Packer) collect(srcPath, destPath, relPath string) ([]packEntry, error) {
+	info, err := os.Lstat(srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if relPath != "" && p.matcher.Ignored(relPath, info.IsDir()) {
+		return nil, nil
+	}
+
+	entries := []packEntry{{srcPath: srcPath, destPath: destPath, info: info}}
+	if !info.IsDir() {
+		return entries, nil
+	}
+
+	children, err := ioutil.ReadDir(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+
+	for _, child := range children {
+		childRel := child.Name()
+		if relPath != "" {
+			childRel = relPath + "/" + child.Name()
+		}
+		childEntries, err := p.collect(filepath.Join(srcPath, child.Name()), filepath.Join(destPath, child.Name()), childRel)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, childEntries...)
+	}
+	return entries, nil
+}
+
+// normalizeHeader zeroes timestamps and collapses mode bits to their
+// permission bits so identical trees produce byte-identical tar headers
+// regardless of when they were written to disk or the umask they were
+// created under.
+func normalizeHeader(hdr *taro.Header) {
+	hdr.ModTime = time.Unix(0, 0).UTC()
+	hdr.AccessTime = hdr.ModTime
+	hdr.ChangeTime = hdr.ModTime
+	hdr.Uid, hdr.Gid = 0, 0
+	hdr.Uname, hdr.Gname = "", ""
+	hdr.Mode &= 0777
+}
+
+func writeFileBody(tw *taro.Writer, path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(tw, f)
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,461 @@
+package occlient
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// SyncOptions configures SyncFiles' rsync-style incremental transfer.
+type SyncOptions struct {
+	// BlockSize is the fixed block size used for the rolling checksum, in
+	// bytes. Defaults to 4096 when zero.
+	BlockSize int
+}
+
+// Stats summarizes what a SyncFiles call actually transferred.
+type Stats struct {
+	FilesChanged     int
+	FilesDeleted     int
+	BytesTransferred int64
+}
+
+const defaultSyncBlockSize = 4096
+
+// remoteFileMeta is one line of the remote manifest: relative path, size,
+// mtime (unix seconds, fractional) and an md5 digest, produced by
+// remoteManifestCmd.
+type remoteFileMeta struct {
+	relPath string
+	size    int64
+	mtime   string
+	md5sum  string
+}
+
+// remoteManifestCmd lists every regular file under remotePath along with its
+// size, mtime and content hash, so SyncFiles can cheaply skip files that are
+// unchanged by the rsync "quick check" (same size + mtime) without needing
+// to transfer anything.
+func remoteManifestCmd(remotePath string) []string {
+	script := fmt.Sprintf(
+		`cd %q && find . -type f -printf '%%P %%s %%T@ ' -exec md5sum {} \; | awk '{print $1, $2, $3, $4}'`,
+		remotePath,
+	)
+	return []string{"sh", "-c", script}
+}
+
+// SyncFiles performs an incremental, rsync-style push of localPath to
+// remotePath inside container, avoiding a full re-tar of the tree on every
+// `odo push`/watch cycle: unchanged files (same size/mtime) are skipped
+// outright, changed files are transferred as a block-diff against the
+// remote copy, and files no longer present locally are deleted remotely.
+// Falls back to a full archive copy (CopyToContainer) when no remote manifest
+// can be obtained, and to a plain copy of the single file when the
+// block-diff helper can't be used for it (e.g. the remote copy doesn't exist
+// yet).
+func (c *Client) SyncFiles(podName, container, localPath, remotePath string, opts SyncOptions) (Stats, error) {
+	blockSize := opts.BlockSize
+	if blockSize <= 0 {
+		blockSize = defaultSyncBlockSize
+	}
+
+	var stats Stats
+
+	remoteManifest, err := c.remoteManifest(podName, container, remotePath)
+	if err != nil {
+		// No remote helper / path doesn't exist yet: fall back to a full copy.
+		glog.V(4).Infof("unable to get remote manifest for %s, falling back to full sync: %v", remotePath, err)
+		return c.fullSync(podName, container, localPath, remotePath)
+	}
+
+	localFiles := map[string]os.FileInfo{}
+	err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(localPath, path)
+		if err != nil {
+			return err
+		}
+		localFiles[filepath.ToSlash(rel)] = info
+		return nil
+	})
+	if err != nil {
+		return stats, errors.Wrap(err, "unable to walk local path")
+	}
+
+	for rel, info := range localFiles {
+		remote, ok := remoteManifest[rel]
+		// find prints the mtime as fractional seconds; it is compared at
+		// one-second resolution, the precision of the tar headers fullSync
+		// writes.
+		remoteMtime, mtimeErr := strconv.ParseFloat(remote.mtime, 64)
+		if ok && mtimeErr == nil && remote.size == info.Size() &&
+			int64(math.Round(remoteMtime)) == info.ModTime().Round(time.Second).Unix() {
+			continue // unchanged, skip (rsync "quick check")
+		}
+
+		n, err := c.syncOneFile(podName, container, filepath.Join(localPath, rel), filepath.ToSlash(filepath.Join(remotePath, rel)), blockSize, ok)
+		if err != nil {
+			return stats, errors.Wrapf(err, "unable to sync %s", rel)
+		}
+		stats.FilesChanged++
+		stats.BytesTransferred += n
+	}
+
+	var toDelete []string
+	for rel := range remoteManifest {
+		if _, ok := localFiles[rel]; !ok {
+			toDelete = append(toDelete, filepath.ToSlash(filepath.Join(remotePath, rel)))
+		}
+	}
+	if len(toDelete) > 0 {
+		rmCmd := append([]string{"rm", "-f"}, toDelete...)
+		if err := c.execInContainer(podName, container, rmCmd, nil, nil, nil, false); err != nil {
+			return stats, errors.Wrap(err, "unable to delete removed files remotely")
+		}
+		stats.FilesDeleted = len(toDelete)
+	}
+
+	return stats, nil
+}
+
+// remoteManifest runs remoteManifestCmd in the pod and parses its output.
+func (c *Client) remoteManifest(podName, container, remotePath string) (map[string]remoteFileMeta, error) {
+	var stdout strings.Builder
+	if err := c.execInContainer(podName, container, remoteManifestCmd(remotePath), &stdout, nil, nil, false); err != nil {
+		return nil, err
+	}
+
+	manifest := map[string]remoteFileMeta{}
+	scanner := bufio.NewScanner(strings.NewReader(stdout.String()))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		rel := strings.TrimPrefix(fields[0], "./")
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		manifest[rel] = remoteFileMeta{relPath: rel, size: size, mtime: fields[2], md5sum: fields[3]}
+	}
+	return manifest, nil
+}
+
+// fullSync copies the contents of localPath to remotePath wholesale - the
+// fallback path used when no remote manifest could be obtained (e.g. first
+// push).
+func (c *Client) fullSync(podName, container, localPath, remotePath string) (Stats, error) {
+	packer, err := NewPacker(localPath, PackOptions{})
+	if err != nil {
+		return Stats{}, errors.Wrap(err, "unable to build archive")
+	}
+
+	// Entries are named relative to localPath ("." and its contents), so they
+	// land directly under remotePath, where the incremental path expects them.
+	entries, err := packer.collect(filepath.Clean(localPath), ".", "")
+	if err != nil {
+		return Stats{}, errors.Wrap(err, "unable to walk local path")
+	}
+	var stats Stats
+	for _, entry := range entries {
+		if entry.info.Mode().IsRegular() {
+			stats.FilesChanged++
+			stats.BytesTransferred += entry.info.Size()
+		}
+	}
+
+	chunks, err := packer.Chunks(".")
+	if err != nil {
+		return Stats{}, errors.Wrap(err, "unable to pack files for sync")
+	}
+
+	for _, chunk := range chunks {
+		if err := c.CopyToContainer(podName, container, remotePath, bytes.NewReader(chunk), CopyOptions{Extract: true}); err != nil {
+			return Stats{}, err
+		}
+	}
+	return stats, nil
+}
+
+// block is one fixed-size chunk of the remote file, identified by its weak
+// (Adler-32) and strong (MD5) checksums.
+type block struct {
+	index  int
+	weak   uint32
+	strong [md5.Size]byte
+}
+
+// syncOneFile computes the block-level delta between the remote and local
+// copies of a single file and transfers it as literal+block-reference
+// instructions, returning the number of literal bytes sent. When the remote
+// file doesn't exist yet (remoteExists is false) it's copied in full.
+func (c *Client) syncOneFile(podName, container, localFile, remoteFile string, blockSize int, remoteExists bool) (int64, error) {
+	if !remoteExists {
+		return c.copyLiteralFile(podName, container, localFile, remoteFile)
+	}
+
+	remoteBlocks, err := c.remoteBlockChecksums(podName, container, remoteFile, blockSize)
+	if err != nil {
+		// Helper unavailable on this image/remote file: fall back to a plain copy.
+		return c.copyLiteralFile(podName, container, localFile, remoteFile)
+	}
+
+	instructions, literalBytes, err := diffAgainstBlocks(localFile, blockSize, remoteBlocks)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := c.reconstructRemoteFile(podName, container, remoteFile, blockSize, instructions); err != nil {
+		return 0, err
+	}
+	return literalBytes, nil
+}
+
+// copyLiteralFile copies localFile to remoteFile via a plain (non-delta)
+// stream.
+func (c *Client) copyLiteralFile(podName, container, localFile, remoteFile string) (int64, error) {
+	f, err := os.Open(localFile)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := c.execInContainer(podName, container, []string{"sh", "-c", fmt.Sprintf("mkdir -p %q && cat > %q", filepath.ToSlash(filepath.Dir(remoteFile)), remoteFile)}, nil, nil, f, false); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// remoteBlockChecksums asks the pod for the strong (MD5) checksum of every
+// blockSize-byte block of remoteFile, computed by a short inline shell
+// helper so no custom binary needs to be present in the image. The weak
+// (Adler-32) checksum isn't computable this way without a helper binary, so
+// the returned blocks all carry weak == 0 and diffAgainstBlocks falls back
+// to comparing every block's strong checksum - correct, if not as fast as
+// a real rolling-checksum bucket lookup would be.
+func (c *Client) remoteBlockChecksums(podName, container, remoteFile string, blockSize int) ([]block, error) {
+	script := fmt.Sprintf(
+		`i=0; while dd if=%q bs=%d skip=$i count=1 2>/dev/null | dd bs=%d count=1 2>/dev/null > /tmp/.odo-block.$$; [ -s /tmp/.odo-block.$$ ]; do md5sum /tmp/.odo-block.$$; i=$((i+1)); done; rm -f /tmp/.odo-block.$$`,
+		remoteFile, blockSize, blockSize,
+	)
+
+	var stdout strings.Builder
+	if err := c.execInContainer(podName, container, []string{"sh", "-c", script}, &stdout, nil, nil, false); err != nil {
+		return nil, err
+	}
+
+	var blocks []block
+	scanner := bufio.NewScanner(strings.NewReader(stdout.String()))
+	for i := 0; scanner.Scan(); i++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		sum, err := hex.DecodeString(fields[0])
+		if err != nil || len(sum) != md5.Size {
+			continue
+		}
+		var strong [md5.Size]byte
+		copy(strong[:], sum)
+		blocks = append(blocks, block{index: i, strong: strong})
+	}
+	return blocks, nil
+}
+
+// instruction is one element of the instruction stream sent to
+// reconstructRemoteFile: either a reference to a remote block to keep, or a
+// run of literal bytes to write.
+type instruction struct {
+	blockIndex int  // >= 0 for a block reference
+	literal    bool // true for a literal run, consuming data from literalData
+	data       []byte
+}
+
+// adlerMod is the modulus Adler-32 (RFC 1950) sums wrap at.
+const adlerMod = 65521
+
+// rollingChecksum is an Adler-32 checksum maintained over a sliding window,
+// so advancing the window by one byte costs O(1) (add the incoming byte,
+// subtract the outgoing one) instead of re-summing the whole window.
+type rollingChecksum struct {
+	a, b   uint32
+	window int
+}
+
+// newRollingChecksum seeds a rollingChecksum from window's initial contents.
+func newRollingChecksum(window []byte) *rollingChecksum {
+	var a uint32 = 1
+	var b uint32
+	for i, c := range window {
+		a = (a + uint32(c)) % adlerMod
+		b = (b + uint32(len(window)-i)*uint32(c)) % adlerMod
+	}
+	b = (b + uint32(len(window))) % adlerMod
+	return &rollingChecksum{a: a, b: b, window: len(window)}
+}
+
+// Sum returns the Adler-32 value of the current window.
+func (r *rollingChecksum) Sum() uint32 {
+	return (r.b << 16) | r.a
+}
+
+// Roll advances the window by one byte: out leaves at the front, in joins
+// at the back. The window length is unchanged.
+func (r *rollingChecksum) Roll(out, in byte) {
+	a := (int64(r.a) - int64(out) + int64(in)) % adlerMod
+	a = (a + adlerMod) % adlerMod
+
+	b := (int64(r.b) + a - 1 - int64(r.window)*int64(out)) % adlerMod
+	b = (b + adlerMod) % adlerMod
+
+	r.a, r.b = uint32(a), uint32(b)
+}
+
+// diffAgainstBlocks rolls a blockSize-wide Adler-32 window across localFile,
+// looking for a remoteBlocks entry whose weak checksum matches (and, on a
+// weak match, confirming with the strong MD5 checksum before accepting), and
+// emits a literal+block-reference instruction stream. When remoteBlocks
+// carries real weak (Adler-32) checksums this narrows candidates the way a
+// real rsync does; remoteBlockChecksums currently can't compute those
+// remotely (see its doc comment), so every block is also checked under the
+// zero-weak bucket - correct, just without the full weak-checksum speedup a
+// real remote rolling checksum would give.
+func diffAgainstBlocks(localFile string, blockSize int, remoteBlocks []block) ([]instruction, int64, error) {
+	data, err := ioutil.ReadFile(localFile)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byWeak := map[uint32][]block{}
+	for _, b := range remoteBlocks {
+		byWeak[b.weak] = append(byWeak[b.weak], b)
+	}
+
+	var instructions []instruction
+	var literalBytes int64
+	var literalRun []byte
+
+	flushLiteral := func() {
+		if len(literalRun) > 0 {
+			instructions = append(instructions, instruction{blockIndex: -1, literal: true, data: literalRun})
+			literalBytes += int64(len(literalRun))
+			literalRun = nil
+		}
+	}
+
+	var roll *rollingChecksum
+	windowEnd := 0 // exclusive end of the window currently tracked by roll
+
+	i := 0
+	for i < len(data) {
+		if roll == nil {
+			end := i + blockSize
+			if end > len(data) {
+				end = len(data)
+			}
+			roll = newRollingChecksum(data[i:end])
+			windowEnd = end
+		}
+
+		weak := roll.Sum()
+		candidates := byWeak[weak]
+		if weak != 0 {
+			candidates = append(candidates, byWeak[0]...)
+		}
+
+		matched := false
+		if len(candidates) > 0 {
+			strong := md5.Sum(data[i:windowEnd])
+			for _, cand := range candidates {
+				if cand.strong == strong {
+					flushLiteral()
+					instructions = append(instructions, instruction{blockIndex: cand.index})
+					matched = true
+					break
+				}
+			}
+		}
+
+		if matched {
+			i = windowEnd
+			roll = nil
+			continue
+		}
+
+		// No match at this offset: keep the window at blockSize wide by
+		// rolling the checksum forward one byte (O(1)) instead of re-hashing
+		// it, and emit the byte that fell out the front as a literal.
+		literalRun = append(literalRun, data[i])
+		if windowEnd < len(data) {
+			roll.Roll(data[i], data[windowEnd])
+			windowEnd++
+		} else {
+			// Already at EOF: the window can only shrink from here, so let
+			// the next iteration rebuild it fresh over the remaining tail.
+			roll = nil
+		}
+		i++
+	}
+	flushLiteral()
+
+	return instructions, literalBytes, nil
+}
+
+// reconstructRemoteFile rewrites remoteFile in the pod from instructions by
+// exec'ing a small shell helper that reads a length-prefixed instruction
+// stream on stdin: each entry is either "B <index>\n" (copy blockSize bytes
+// starting at block <index> of the *current* remote file) or "L <n>\n"
+// followed by n raw bytes (write them literally). The helper assembles the
+// result into a temp file and renames it over remoteFile so a failed/partial
+// sync never corrupts the original.
+func (c *Client) reconstructRemoteFile(podName, container, remoteFile string, blockSize int, instructions []instruction) error {
+	script := fmt.Sprintf(`
+set -e
+src=%q
+tmp=$(mktemp)
+trap 'rm -f "$tmp"' EXIT
+while read -r op arg; do
+  case "$op" in
+    B) dd if="$src" bs=%d skip="$arg" count=1 2>/dev/null >> "$tmp" ;;
+    L) dd bs="$arg" count=1 2>/dev/null >> "$tmp" ;;
+  esac
+done
+mv "$tmp" "$src"
+`, remoteFile, blockSize)
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer writer.Close()
+		for _, instr := range instructions {
+			if instr.literal {
+				fmt.Fprintf(writer, "L %d\n", len(instr.data))
+				writer.Write(instr.data)
+			} else {
+				fmt.Fprintf(writer, "B %d\n", instr.blockIndex)
+			}
+		}
+	}()
+
+	return c.execInContainer(podName, container, []string{"sh", "-c", script}, nil, nil, reader, false)
+}